@@ -2,7 +2,10 @@ package dynamicstruct
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
+	"strings"
+	"sync"
 
 	"github.com/goldeneggg/structil/util"
 )
@@ -31,9 +34,15 @@ var (
 )
 
 // Builder is the interface that builds a dynamic and runtime struct.
+//
+// Builder is safe for concurrent use by multiple goroutines.
+// Each method is applied atomically, but a chain of methods is not.
+// For deriving variants from a common base concurrently, Clone the base in each goroutine and extend the clone.
 type Builder struct {
+	mu    sync.RWMutex
 	name  string
 	bfMap builderFieldMap
+	keys  []string // field names in added order
 	err   error
 }
 
@@ -45,25 +54,165 @@ func NewBuilder() *Builder {
 	}
 }
 
+// NewBuilderFromType returns a concrete Builder that has the exported fields of the struct type typ.
+// If typ is a pointer type, the element type of it is used.
+// The struct name is the name of typ, or the default name if typ is unnamed.
+// The exported fields of embedded structs are promoted to the fields of the Builder like encoding/json,
+// so that the JSON of the built struct has the same shape as typ.
+// Embedded structs with json tag names and embedded non-struct types are added as normal fields named by their type names.
+//
+// If typ is not a struct type, the error is returned by Build.
+func NewBuilderFromType(typ reflect.Type) *Builder {
+	b := NewBuilder()
+
+	if typ != nil && typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		b.err = fmt.Errorf("type [%v] is not a struct type", typ)
+		return b
+	}
+
+	if typ.Name() != "" {
+		b.SetStructName(typ.Name())
+	}
+
+	// VisibleFields excludes the promoted fields that are shadowed or ambiguous
+	for _, sf := range reflect.VisibleFields(typ) {
+		// reflect.StructOf does not support unexported fields
+		if !sf.IsExported() || isPromotable(sf) || !isPromoted(typ, sf.Index) {
+			continue
+		}
+		b.AddTypeWithTag(sf.Name, sf.Type, string(sf.Tag))
+	}
+
+	return b
+}
+
+// isPromotable reports whether the fields of the embedded field sf are promoted like encoding/json.
+func isPromotable(sf reflect.StructField) bool {
+	if !sf.Anonymous {
+		return false
+	}
+	t := sf.Type
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	return t.Kind() == reflect.Struct && name == ""
+}
+
+// isPromoted reports whether all embedded fields on the index sequence of a field of typ are promotable.
+func isPromoted(typ reflect.Type, index []int) bool {
+	for _, i := range index[:len(index)-1] {
+		sf := typ.Field(i)
+		if !isPromotable(sf) {
+			return false
+		}
+		typ = sf.Type
+		if typ.Kind() == reflect.Ptr {
+			typ = typ.Elem()
+		}
+	}
+	return true
+}
+
+// NewBuilderFrom returns a concrete Builder that has the exported fields of the struct type of i.
+// i must be a struct or struct pointer. See NewBuilderFromType.
+func NewBuilderFrom(i interface{}) *Builder {
+	return NewBuilderFromType(reflect.TypeOf(i))
+}
+
 type builderField struct {
-	name string
-	typ  reflect.Type
-	tag  reflect.StructTag
+	name        string
+	typ         reflect.Type
+	tag         reflect.StructTag
+	meta        FieldMeta
+	nestedMetas map[string]FieldMeta // metadata of nested struct fields keyed by relative paths
+	ref         *Ref
+	nestedRefs  map[string]*Ref // Refs of nested struct fields keyed by relative paths
 }
 
 type builderFieldMap map[string]*builderField
 
+// Clone returns a new Builder that has the copies of the fields, the name and the error of this.
+// Changes of the returned Builder do not affect this, and vice versa.
+// Note that Refs attached to fields are shared (not copied), so resolving a Ref by Ref.Resolve affects both.
+func (b *Builder) Clone() *Builder {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	c := &Builder{
+		name:  b.name,
+		bfMap: make(builderFieldMap, len(b.bfMap)),
+		keys:  make([]string, len(b.keys)),
+		err:   b.err,
+	}
+	copy(c.keys, b.keys)
+	for key, bf := range b.bfMap {
+		c.bfMap[key] = bf.clone()
+	}
+
+	return c
+}
+
+func (bf *builderField) clone() *builderField {
+	c := *bf
+	if bf.nestedMetas != nil {
+		c.nestedMetas = make(map[string]FieldMeta, len(bf.nestedMetas))
+		for p, m := range bf.nestedMetas {
+			c.nestedMetas[p] = m
+		}
+	}
+	if bf.nestedRefs != nil {
+		c.nestedRefs = make(map[string]*Ref, len(bf.nestedRefs))
+		for p, r := range bf.nestedRefs {
+			c.nestedRefs[p] = r
+		}
+	}
+	return &c
+}
+
+// Note: the following xxxFieldMap methods must be called with b.mu held.
+
 func (b *Builder) getFieldMap(key string) *builderField {
 	r := b.bfMap[key]
 	return r
 }
 
 func (b *Builder) putFieldMap(key string, bf *builderField) {
+	if _, ok := b.bfMap[key]; !ok {
+		b.keys = append(b.keys, key)
+	}
 	b.bfMap[key] = bf
 }
 
 func (b *Builder) deleteFieldMap(key string) {
+	if _, ok := b.bfMap[key]; !ok {
+		return
+	}
+
 	delete(b.bfMap, key)
+	for i, k := range b.keys {
+		if k == key {
+			b.keys = append(b.keys[:i], b.keys[i+1:]...)
+			break
+		}
+	}
+}
+
+func (b *Builder) renameFieldMap(oldKey string, newKey string) {
+	bf := b.bfMap[oldKey]
+	bf.name = newKey
+
+	delete(b.bfMap, oldKey)
+	b.bfMap[newKey] = bf
+	for i, k := range b.keys {
+		if k == oldKey {
+			b.keys[i] = newKey
+			break
+		}
+	}
 }
 
 func (b *Builder) hasFieldMap(key string) bool {
@@ -76,6 +225,15 @@ func (b *Builder) lenFieldMap() int {
 }
 
 func (b *Builder) addFieldFunc(name string, isPtr bool, tag string, f func() reflect.Type) *Builder {
+	return b.addFieldFuncWith(name, isPtr, tag, f, nil)
+}
+
+// addFieldFuncWith is same as addFieldFunc, and initField is called for the new field (if not nil) under the same lock
+// so that the field is never seen without its metadata and Refs.
+func (b *Builder) addFieldFuncWith(name string, isPtr bool, tag string, f func() reflect.Type, initField func(bf *builderField)) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	defer func() {
 		err := util.RecoverToError(recover())
 
@@ -91,11 +249,15 @@ func (b *Builder) addFieldFunc(name string, isPtr bool, tag string, f func() ref
 		typ = reflect.PtrTo(typ)
 	}
 
-	b.putFieldMap(name, &builderField{
+	bf := &builderField{
 		name: name,
 		typ:  typ,
 		tag:  reflect.StructTag(tag),
-	})
+	}
+	if initField != nil {
+		initField(bf)
+	}
+	b.putFieldMap(name, bf)
 
 	return b
 }
@@ -314,7 +476,13 @@ func (b *Builder) AddStruct(name string, i interface{}, isPtr bool) *Builder {
 // AddStructWithTag returns a Builder that was added a struct field with tag named by name parameter.
 // Type of struct is type of i.
 func (b *Builder) AddStructWithTag(name string, i interface{}, isPtr bool, tag string) *Builder {
-	f := func() reflect.Type {
+	b.addFieldFunc(name, isPtr, tag, structTypeFunc(i))
+
+	return b
+}
+
+func structTypeFunc(i interface{}) func() reflect.Type {
+	return func() reflect.Type {
 		iType := reflect.TypeOf(i)
 		if iType.Kind() == reflect.Ptr {
 			iType = iType.Elem()
@@ -325,9 +493,6 @@ func (b *Builder) AddStructWithTag(name string, i interface{}, isPtr bool, tag s
 		}
 		return reflect.StructOf(fields)
 	}
-	b.addFieldFunc(name, isPtr, tag, f)
-
-	return b
 }
 
 // AddStructPtr returns a Builder that was added a struct pointer field named by name parameter.
@@ -376,6 +541,27 @@ func (b *Builder) AddInterfaceWithTag(name string, isPtr bool, tag string) *Buil
 	return b
 }
 
+// AddType returns a Builder that was added a field named by name parameter.
+// Type of field is typ.
+func (b *Builder) AddType(name string, typ reflect.Type) *Builder {
+	b.AddTypeWithTag(name, typ, "")
+	return b
+}
+
+// AddTypeWithTag returns a Builder that was added a field with tag named by name parameter.
+// Type of field is typ.
+func (b *Builder) AddTypeWithTag(name string, typ reflect.Type, tag string) *Builder {
+	f := func() reflect.Type {
+		if typ == nil {
+			panic("typ must not be nil")
+		}
+		return typ
+	}
+	b.addFieldFunc(name, false, tag, f)
+
+	return b
+}
+
 // AddDynamicStruct returns a Builder that was added a DynamicStruct field named by name parameter.
 func (b *Builder) AddDynamicStruct(name string, ds *DynamicStruct, isPtr bool) *Builder {
 	b.AddDynamicStructWithTag(name, ds, isPtr, "")
@@ -384,7 +570,10 @@ func (b *Builder) AddDynamicStruct(name string, ds *DynamicStruct, isPtr bool) *
 
 // AddDynamicStructWithTag returns a Builder that was added a DynamicStruct field with tag named by name parameter.
 func (b *Builder) AddDynamicStructWithTag(name string, ds *DynamicStruct, isPtr bool, tag string) *Builder {
-	b.AddStructWithTag(name, ds.NewInterface(), isPtr, tag)
+	initField := func(bf *builderField) {
+		bf.setNested(ds)
+	}
+	b.addFieldFuncWith(name, isPtr, tag, structTypeFunc(ds.NewInterface()), initField)
 
 	return b
 }
@@ -406,35 +595,76 @@ func (b *Builder) AddDynamicStructSlice(name string, ds *DynamicStruct) *Builder
 
 // AddDynamicStructSliceWithTag returns a Builder that was added a DynamicStruct slice field with tag named by name parameter.
 func (b *Builder) AddDynamicStructSliceWithTag(name string, ds *DynamicStruct, tag string) *Builder {
-	b.AddSliceWithTag(name, ds.NewInterface(), tag)
+	f := func() reflect.Type {
+		return reflect.SliceOf(reflect.TypeOf(ds.NewInterface()))
+	}
+	initField := func(bf *builderField) {
+		bf.setNested(ds)
+	}
+	b.addFieldFuncWith(name, false, tag, f, initField)
 
 	return b
 }
 
+// putNested keeps the metadata and Refs of ds for the nested struct field.
+func (b *Builder) putNested(name string, ds *DynamicStruct) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if bf := b.getFieldMap(name); bf != nil {
+		bf.setNested(ds)
+	}
+}
+
+// setNested sets the metadata and Refs of ds as those of the nested struct fields.
+func (bf *builderField) setNested(ds *DynamicStruct) {
+	if len(ds.metas) > 0 {
+		bf.nestedMetas = ds.Metas()
+	}
+	if len(ds.refs) > 0 {
+		bf.nestedRefs = ds.Refs()
+	}
+}
+
 // NumField returns the number of built struct fields.
 func (b *Builder) NumField() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
 	return b.lenFieldMap()
 }
 
 // Exists returns true if the specified name field exists
 func (b *Builder) Exists(name string) bool {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
 	return b.hasFieldMap(name)
 }
 
 // GetStructName returns the name of this DynamicStruct.
 func (b *Builder) GetStructName() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
 	return b.name
 }
 
 // SetStructName returns a Builder that was set the name of DynamicStruct.
 // Default name is "DynamicStruct"
 func (b *Builder) SetStructName(name string) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	b.name = name
 	return b
 }
 
 // GetTag returns the tag of the specified name field.
 func (b *Builder) GetTag(name string) string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
 	if b.hasFieldMap(name) {
 		return string(b.getFieldMap(name).tag)
 	}
@@ -444,6 +674,9 @@ func (b *Builder) GetTag(name string) string {
 // SetTag returns a Builder that was set the tag for the specific field.
 // Expected tag string is 'TYPE1:"FIELDNAME1" TYPEn:"FIELDNAMEn"' format (e.g. json:"id" etc)
 func (b *Builder) SetTag(name string, tag string) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	if b.hasFieldMap(name) {
 		b.getFieldMap(name).tag = reflect.StructTag(tag)
 	}
@@ -452,10 +685,35 @@ func (b *Builder) SetTag(name string, tag string) *Builder {
 
 // Remove returns a Builder that was removed a field named by name parameter.
 func (b *Builder) Remove(name string) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
 	b.deleteFieldMap(name)
 	return b
 }
 
+// Rename returns a Builder that was renamed a field from oldName to newName.
+// The position and the tag of the field are kept.
+// If newName field already exists, the error is returned by Build.
+func (b *Builder) Rename(oldName string, newName string) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.hasFieldMap(oldName) || oldName == newName {
+		return b
+	}
+
+	if b.hasFieldMap(newName) {
+		if b.err == nil {
+			b.err = fmt.Errorf("field %s already exists", newName)
+		}
+		return b
+	}
+
+	b.renameFieldMap(oldName, newName)
+	return b
+}
+
 // Build returns a concrete struct pointer built by Builder.
 func (b *Builder) Build() (*DynamicStruct, error) {
 	return b.build(true)
@@ -467,25 +725,67 @@ func (b *Builder) BuildNonPtr() (*DynamicStruct, error) {
 }
 
 func (b *Builder) build(isPtr bool) (ds *DynamicStruct, err error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
 	if b.err != nil {
 		err = b.err
 		return
 	}
 
-	var i int
 	// DON'T use b.lenFieldMap() method because of dead lock
-	fields := make([]reflect.StructField, len(b.bfMap))
+	fields := make([]reflect.StructField, len(b.keys))
 
-	for key, bf := range b.bfMap {
+	var metas map[string]FieldMeta
+	var refs map[string]*Ref
+
+	// fields are ordered by added order
+	for i, key := range b.keys {
+		bf := b.bfMap[key]
 		fields[i] = reflect.StructField{
 			Name: key,
 			Type: bf.typ,
 			Tag:  bf.tag,
 		}
-		i++
+
+		if !bf.meta.IsZero() || len(bf.nestedMetas) > 0 {
+			if metas == nil {
+				metas = make(map[string]FieldMeta)
+			}
+			if !bf.meta.IsZero() {
+				metas[key] = bf.meta
+			}
+			for p, m := range bf.nestedMetas {
+				metas[key+"."+p] = m
+			}
+		}
+
+		if bf.ref != nil || len(bf.nestedRefs) > 0 {
+			if refs == nil {
+				refs = make(map[string]*Ref)
+			}
+			if bf.ref != nil {
+				refs[key] = bf.ref
+			}
+			for p, r := range bf.nestedRefs {
+				refs[key+"."+p] = r
+			}
+		}
 	}
 
-	ds, err = newDynamicStruct(fields, isPtr, b.GetStructName())
+	ds, err = newDynamicStruct(fields, isPtr, b.name)
+	if err == nil {
+		ds.metas = metas
+		ds.refs = refs
+
+		// self references refer to the DynamicStruct built this time
+		self := &Ref{name: ds.name, ds: ds}
+		for p, r := range refs {
+			if r.name == ds.name {
+				refs[p] = self
+			}
+		}
+	}
 
 	return
 }
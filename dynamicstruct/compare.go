@@ -0,0 +1,322 @@
+package dynamicstruct
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// EqualOptions is the options for Equal.
+type EqualOptions struct {
+	// IgnoreTags ignores the differences of field tags.
+	IgnoreTags bool
+}
+
+// Equal reports whether a and b have the same structure.
+// The order of fields (including the fields of nested structs) is ignored,
+// and the names and the pointer modes of DynamicStructs are not compared.
+func Equal(a *DynamicStruct, b *DynamicStruct, opts EqualOptions) bool {
+	return equalFields(a.fields, b.fields, opts)
+}
+
+func equalFields(as []reflect.StructField, bs []reflect.StructField, opts EqualOptions) bool {
+	if len(as) != len(bs) {
+		return false
+	}
+
+	bm := fieldMap(bs)
+	for _, af := range as {
+		bf, ok := bm[af.Name]
+		if !ok {
+			return false
+		}
+		if !opts.IgnoreTags && af.Tag != bf.Tag {
+			return false
+		}
+		if !equalType(af.Type, bf.Type, opts) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func equalType(at reflect.Type, bt reflect.Type, opts EqualOptions) bool {
+	if at == bt {
+		return true
+	}
+
+	if isMergeableStructs(at, bt) {
+		return equalFields(structFields(at), structFields(bt), opts)
+	}
+
+	if at.Kind() != bt.Kind() || at.Name() != "" || bt.Name() != "" {
+		return false
+	}
+
+	switch at.Kind() {
+	case reflect.Ptr, reflect.Slice:
+		return equalType(at.Elem(), bt.Elem(), opts)
+	case reflect.Array:
+		return at.Len() == bt.Len() && equalType(at.Elem(), bt.Elem(), opts)
+	case reflect.Map:
+		return equalType(at.Key(), bt.Key(), opts) && equalType(at.Elem(), bt.Elem(), opts)
+	}
+
+	return false
+}
+
+// IsAssignableFrom reports whether all values of src can be held by this without loss.
+// This is true if all fields of src exist in this with the same or wider types (e.g. int to int64, T to *T).
+// Tags and the fields that exist only in this are ignored.
+func (ds *DynamicStruct) IsAssignableFrom(src *DynamicStruct) bool {
+	return isWiderFields(src.fields, ds.fields)
+}
+
+// isWiderType reports whether wt can hold all values of t.
+func isWiderType(t reflect.Type, wt reflect.Type) bool {
+	if t == wt || wt == interfaceType {
+		return true
+	}
+
+	if isMergeableStructs(t, wt) {
+		return isWiderFields(structFields(t), structFields(wt))
+	}
+
+	if wt.Kind() == reflect.Ptr {
+		return isWiderType(indirectType(t), wt.Elem())
+	}
+
+	if isPredeclaredNumber(t) && isPredeclaredNumber(wt) {
+		return isWiderNumber(t, wt)
+	}
+
+	if t.Kind() != wt.Kind() || t.Name() != "" || wt.Name() != "" {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Slice:
+		return isWiderType(t.Elem(), wt.Elem())
+	case reflect.Array:
+		return t.Len() == wt.Len() && isWiderType(t.Elem(), wt.Elem())
+	case reflect.Map:
+		return t.Key() == wt.Key() && isWiderType(t.Elem(), wt.Elem())
+	}
+
+	return false
+}
+
+func isWiderFields(fs []reflect.StructField, wfs []reflect.StructField) bool {
+	wm := fieldMap(wfs)
+	for _, f := range fs {
+		wf, ok := wm[f.Name]
+		if !ok || !isWiderType(f.Type, wf.Type) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isWiderNumber reports whether the number type wt can hold all values of the number type t.
+// Integers are held by floats only if they fit in the mantissa exactly
+// (e.g. int32 to float64, but not int64 to float64 because large IDs lose precision).
+func isWiderNumber(t reflect.Type, wt reflect.Type) bool {
+	switch {
+	case isFloat(wt):
+		if isFloat(t) {
+			return t.Bits() <= wt.Bits()
+		}
+		// the mantissa of float64 is 53 bits and float32 is 24 bits
+		return t.Bits() <= wt.Bits()/2
+	case isFloat(t):
+		return false
+	case isUnsigned(wt):
+		return isUnsigned(t) && t.Bits() <= wt.Bits()
+	case isUnsigned(t):
+		return t.Bits() < wt.Bits()
+	default:
+		return t.Bits() <= wt.Bits()
+	}
+}
+
+func fieldMap(fields []reflect.StructField) map[string]reflect.StructField {
+	m := make(map[string]reflect.StructField, len(fields))
+	for _, f := range fields {
+		m[f.Name] = f
+	}
+	return m
+}
+
+// ChangeKind is the kind of a Change.
+type ChangeKind int
+
+const (
+	// FieldAdded means that the field exists only in the new DynamicStruct.
+	FieldAdded ChangeKind = iota
+
+	// FieldRemoved means that the field exists only in the old DynamicStruct.
+	FieldRemoved
+
+	// TypeWidened means that the new field type can hold all values of the old field type.
+	TypeWidened
+
+	// TypeNarrowed means that the old field type can hold all values of the new field type, but not vice versa.
+	TypeNarrowed
+
+	// TypeChanged means that the field type is changed incompatibly.
+	TypeChanged
+
+	// TagChanged means that the field tag is changed.
+	TagChanged
+)
+
+var changeKindNames = [...]string{
+	FieldAdded:   "field added",
+	FieldRemoved: "field removed",
+	TypeWidened:  "type widened",
+	TypeNarrowed: "type narrowed",
+	TypeChanged:  "type changed",
+	TagChanged:   "tag changed",
+}
+
+func (k ChangeKind) String() string {
+	if k >= 0 && int(k) < len(changeKindNames) {
+		return changeKindNames[k]
+	}
+	return fmt.Sprintf("ChangeKind(%d)", int(k))
+}
+
+// Change is a difference of a field between old and new DynamicStructs.
+type Change struct {
+	// Path is the field path from the top level struct (e.g. "ObjField.Id").
+	Path string
+
+	// Kind is the kind of this change.
+	Kind ChangeKind
+
+	// Old and New are the field types. Old is nil if the field is added, and New is nil if the field is removed.
+	Old reflect.Type
+	New reflect.Type
+
+	// OldTag and NewTag are the field tags.
+	OldTag reflect.StructTag
+	NewTag reflect.StructTag
+}
+
+// Breaking reports whether this change breaks the compatibility.
+// Added fields and widened types are not breaking, and the others are breaking.
+func (c Change) Breaking() bool {
+	return c.Kind != FieldAdded && c.Kind != TypeWidened
+}
+
+// String returns the human readable description of this.
+func (c Change) String() string {
+	switch c.Kind {
+	case FieldAdded:
+		return fmt.Sprintf("%s: %s (%v)", c.Path, c.Kind, c.New)
+	case FieldRemoved:
+		return fmt.Sprintf("%s: %s (%v)", c.Path, c.Kind, c.Old)
+	case TagChanged:
+		return fmt.Sprintf("%s: %s from %q to %q", c.Path, c.Kind, c.OldTag, c.NewTag)
+	default:
+		return fmt.Sprintf("%s: %s from %v to %v", c.Path, c.Kind, c.Old, c.New)
+	}
+}
+
+// CompatibilityReport is the result of CheckCompatibility.
+type CompatibilityReport struct {
+	// Changes are all changes from old to new.
+	Changes []Change
+}
+
+// Breaking returns the breaking changes of this.
+func (r *CompatibilityReport) Breaking() []Change {
+	var cs []Change
+	for _, c := range r.Changes {
+		if c.Breaking() {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+// IsCompatible reports whether this has no breaking changes.
+func (r *CompatibilityReport) IsCompatible() bool {
+	return len(r.Breaking()) == 0
+}
+
+// CheckCompatibility returns the changes from the old DynamicStruct from to the new DynamicStruct to.
+// Fields of nested structs are compared recursively, and changes are ordered by the fields of from and then to.
+func CheckCompatibility(from *DynamicStruct, to *DynamicStruct) *CompatibilityReport {
+	r := &CompatibilityReport{}
+	r.compareFields(from.fields, to.fields, "")
+	return r
+}
+
+func (r *CompatibilityReport) compareFields(ofs []reflect.StructField, nfs []reflect.StructField, path string) {
+	nm := fieldMap(nfs)
+	om := fieldMap(ofs)
+
+	for _, of := range ofs {
+		p := joinPath(path, of.Name)
+		nf, ok := nm[of.Name]
+		if !ok {
+			r.Changes = append(r.Changes, Change{Path: p, Kind: FieldRemoved, Old: of.Type, OldTag: of.Tag})
+			continue
+		}
+
+		if of.Tag != nf.Tag {
+			r.Changes = append(r.Changes, Change{
+				Path: p, Kind: TagChanged, Old: of.Type, New: nf.Type, OldTag: of.Tag, NewTag: nf.Tag,
+			})
+		}
+		r.compareType(of.Type, nf.Type, p)
+	}
+
+	for _, nf := range nfs {
+		if _, ok := om[nf.Name]; !ok {
+			r.Changes = append(r.Changes, Change{Path: joinPath(path, nf.Name), Kind: FieldAdded, New: nf.Type, NewTag: nf.Tag})
+		}
+	}
+}
+
+func (r *CompatibilityReport) compareType(ot reflect.Type, nt reflect.Type, path string) {
+	if ot == nt {
+		return
+	}
+
+	// compare the fields of nested structs in the same shape of containers
+	oet, net := ot, nt
+	for sameContainer(oet, net) {
+		oet, net = oet.Elem(), net.Elem()
+	}
+	if isMergeableStructs(oet, net) {
+		r.compareFields(structFields(oet), structFields(net), path)
+		return
+	}
+
+	kind := TypeChanged
+	if isWiderType(ot, nt) {
+		kind = TypeWidened
+	} else if isWiderType(nt, ot) {
+		kind = TypeNarrowed
+	}
+	r.Changes = append(r.Changes, Change{Path: path, Kind: kind, Old: ot, New: nt})
+}
+
+func sameContainer(at reflect.Type, bt reflect.Type) bool {
+	if at.Kind() != bt.Kind() || at.Name() != "" || bt.Name() != "" {
+		return false
+	}
+
+	switch at.Kind() {
+	case reflect.Ptr, reflect.Slice:
+		return true
+	case reflect.Array:
+		return at.Len() == bt.Len()
+	case reflect.Map:
+		return at.Key() == bt.Key()
+	}
+	return false
+}
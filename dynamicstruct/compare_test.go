@@ -0,0 +1,155 @@
+package dynamicstruct_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	. "github.com/goldeneggg/structil/dynamicstruct"
+)
+
+func TestEqual(t *testing.T) {
+	t.Parallel()
+
+	nds1, _ := NewBuilder().AddString("A").AddInt("B").BuildNonPtr()
+	nds2, _ := NewBuilder().AddInt("B").AddString("A").BuildNonPtr()
+	nds3, _ := NewBuilder().AddInt("B").AddStringWithTag("A", `json:"a"`).BuildNonPtr()
+
+	base, _ := NewBuilder().
+		SetStructName("Base").
+		AddStringWithTag("Name", `json:"name"`).
+		AddDynamicStructSlice("Items", nds1).
+		Build()
+
+	tests := []struct {
+		name       string
+		b          *Builder
+		opts       EqualOptions
+		wantEqual  bool
+		wantAssign bool
+	}{
+		{
+			name:       "reordered",
+			b:          NewBuilder().AddDynamicStructSlice("Items", nds2).AddStringWithTag("Name", `json:"name"`),
+			wantEqual:  true,
+			wantAssign: true,
+		},
+		{
+			name:       "nested tag changed",
+			b:          NewBuilder().AddDynamicStructSlice("Items", nds3).AddStringWithTag("Name", `json:"name"`),
+			wantEqual:  false,
+			wantAssign: true,
+		},
+		{
+			name:       "nested tag changed with IgnoreTags",
+			b:          NewBuilder().AddDynamicStructSlice("Items", nds3).AddStringWithTag("Name", `json:"name"`),
+			opts:       EqualOptions{IgnoreTags: true},
+			wantEqual:  true,
+			wantAssign: true,
+		},
+		{
+			name:       "field added",
+			b:          NewBuilder().AddDynamicStructSlice("Items", nds1).AddStringWithTag("Name", `json:"name"`).AddBool("Extra"),
+			wantEqual:  false,
+			wantAssign: true,
+		},
+		{
+			name:       "field removed",
+			b:          NewBuilder().AddStringWithTag("Name", `json:"name"`),
+			wantEqual:  false,
+			wantAssign: false,
+		},
+		{
+			name:       "type to pointer",
+			b:          NewBuilder().AddDynamicStructSlice("Items", nds1).AddType("Name", reflect.TypeOf((*string)(nil))),
+			opts:       EqualOptions{IgnoreTags: true},
+			wantEqual:  false,
+			wantAssign: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ds, err := tt.b.BuildNonPtr()
+			if err != nil {
+				t.Fatalf("unexpected error caused by Build: %v", err)
+			}
+
+			if got := Equal(base, ds, tt.opts); got != tt.wantEqual {
+				t.Errorf("unexpected Equal. got: %v, want: %v", got, tt.wantEqual)
+			}
+			if got := Equal(ds, base, tt.opts); got != tt.wantEqual {
+				t.Errorf("unexpected Equal (swapped). got: %v, want: %v", got, tt.wantEqual)
+			}
+			if got := ds.IsAssignableFrom(base); got != tt.wantAssign {
+				t.Errorf("unexpected IsAssignableFrom. got: %v, want: %v", got, tt.wantAssign)
+			}
+		})
+	}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	t.Parallel()
+
+	onds, _ := NewBuilder().AddString("Key").AddType("Count", reflect.TypeOf(int32(0))).BuildNonPtr()
+	nnds, _ := NewBuilder().AddString("Key").AddFloat64("Count").AddBool("Flag").BuildNonPtr()
+
+	old, _ := NewBuilder().
+		AddType("ID", reflect.TypeOf(int32(0))).
+		AddFloat64("Rate").
+		AddType("Big", reflect.TypeOf(int64(0))).
+		AddStringWithTag("Name", `json:"name"`).
+		AddString("Note").
+		AddBool("Removed").
+		AddDynamicStructSlice("Items", onds).
+		Build()
+	cur, _ := NewBuilder().
+		AddType("ID", reflect.TypeOf(int64(0))).
+		AddType("Rate", reflect.TypeOf(float32(0))).
+		AddFloat64("Big").
+		AddStringWithTag("Name", `json:"full_name"`).
+		AddBool("Note").
+		AddDynamicStructSlice("Items", nnds).
+		AddString("Added").
+		Build()
+
+	r := CheckCompatibility(old, cur)
+
+	var got []string
+	for _, c := range r.Changes {
+		got = append(got, c.String())
+	}
+	want := []string{
+		"ID: type widened from int32 to int64",
+		"Rate: type narrowed from float64 to float32",
+		"Big: type changed from int64 to float64",
+		`Name: tag changed from "json:\"name\"" to "json:\"full_name\""`,
+		"Note: type changed from string to bool",
+		"Removed: field removed (bool)",
+		"Items.Count: type widened from int32 to float64",
+		"Items.Flag: field added (bool)",
+		"Added: field added (string)",
+	}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Fatalf("mismatch Changes: (-got +want)\n%s", d)
+	}
+
+	var breaking []string
+	for _, c := range r.Breaking() {
+		breaking = append(breaking, c.Path)
+	}
+	if d := cmp.Diff(breaking, []string{"Rate", "Big", "Name", "Note", "Removed"}); d != "" {
+		t.Fatalf("mismatch Breaking: (-got +want)\n%s", d)
+	}
+	if r.IsCompatible() {
+		t.Errorf("IsCompatible is expected to be false")
+	}
+
+	if r := CheckCompatibility(old, old); len(r.Changes) != 0 || !r.IsCompatible() {
+		t.Errorf("unexpected changes of same DynamicStructs. got: %v", r.Changes)
+	}
+}
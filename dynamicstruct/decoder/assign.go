@@ -0,0 +1,320 @@
+package decoder
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/goldeneggg/structil/dynamicstruct"
+)
+
+// errFallback is returned from assign if the value can not be assigned directly.
+// Then the value is decoded by the marshal and unmarshal round trip.
+var errFallback = errors.New("fallback to the round trip")
+
+// assigner assigns the unmarshaled values (e.g. map[string]interface{}) to the DynamicStruct values directly
+// without the marshal and unmarshal round trip.
+type assigner struct {
+	dt dataType
+}
+
+// fieldKey is the key of the string key map for a struct field.
+type fieldKey struct {
+	index int
+	key   string
+}
+
+type fieldKeysCacheKey struct {
+	typ reflect.Type
+	dt  dataType
+}
+
+// fieldKeysCache caches []fieldKey by fieldKeysCacheKey.
+var fieldKeysCache sync.Map
+
+func (a *assigner) fieldKeys(typ reflect.Type) []fieldKey {
+	ck := fieldKeysCacheKey{typ: typ, dt: a.dt}
+	if fks, ok := fieldKeysCache.Load(ck); ok {
+		return fks.([]fieldKey)
+	}
+
+	fks := make([]fieldKey, 0, typ.NumField())
+	for i := 0; i < typ.NumField(); i++ {
+		sf := typ.Field(i)
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		key := sf.Name
+		if v, ok := sf.Tag.Lookup(a.dt.string()); ok {
+			tag := dynamicstruct.NewTag().Set(a.dt.string(), v)
+			name := tag.Name(a.dt.string())
+			switch {
+			case name == "-":
+				continue
+			case a.dt == typeXML && tag.HasOption(a.dt.string(), "chardata"):
+				key = xmlTextKey
+			case a.dt == typeXML && tag.HasOption(a.dt.string(), "attr"):
+				key = xmlAttrPrefix + name
+			case name != "":
+				key = name
+			}
+		}
+		fks = append(fks, fieldKey{index: i, key: key})
+	}
+
+	fieldKeysCache.Store(ck, fks)
+	return fks
+}
+
+func (a *assigner) assign(dst reflect.Value, src interface{}) error {
+	if src == nil {
+		// same as encoding/json, null does not change the value
+		return nil
+	}
+
+	switch dst.Kind() {
+	case reflect.Interface:
+		if dst.NumMethod() > 0 {
+			return errFallback
+		}
+		dst.Set(reflect.ValueOf(a.plain(src)))
+		return nil
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return a.assign(dst.Elem(), src)
+	case reflect.Struct:
+		m, ok := src.(map[string]interface{})
+		if !ok || !isPlainStruct(dst.Type()) {
+			return a.assignSame(dst, src)
+		}
+		return a.assignStruct(dst, m)
+	case reflect.Slice:
+		s, ok := src.([]interface{})
+		if !ok {
+			return errFallback
+		}
+		sv := reflect.MakeSlice(dst.Type(), len(s), len(s))
+		for i, e := range s {
+			if err := a.assign(sv.Index(i), e); err != nil {
+				return err
+			}
+		}
+		dst.Set(sv)
+		return nil
+	case reflect.Map:
+		m, ok := src.(map[string]interface{})
+		if !ok || dst.Type().Key().Kind() != reflect.String {
+			return errFallback
+		}
+		mv := reflect.MakeMapWithSize(dst.Type(), len(m))
+		for k, e := range m {
+			ev := reflect.New(dst.Type().Elem()).Elem()
+			if err := a.assign(ev, e); err != nil {
+				return err
+			}
+			mv.SetMapIndex(reflect.ValueOf(k).Convert(dst.Type().Key()), ev)
+		}
+		dst.Set(mv)
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, ok := toInt64(src)
+		if !ok || dst.OverflowInt(i) {
+			return errFallback
+		}
+		dst.SetInt(i)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, ok := toUint64(src)
+		if !ok || dst.OverflowUint(u) {
+			return errFallback
+		}
+		dst.SetUint(u)
+		return nil
+	case reflect.Float32, reflect.Float64:
+		f, ok := toFloat64(src)
+		if !ok || dst.OverflowFloat(f) {
+			return errFallback
+		}
+		dst.SetFloat(f)
+		return nil
+	}
+
+	return a.assignSame(dst, src)
+}
+
+// assignSame assigns src if the type of src is same as dst (e.g. string, bool and time.Time).
+func (a *assigner) assignSame(dst reflect.Value, src interface{}) error {
+	sv := reflect.ValueOf(src)
+	if sv.Type() != dst.Type() {
+		return errFallback
+	}
+	dst.Set(sv)
+	return nil
+}
+
+func (a *assigner) assignStruct(dst reflect.Value, m map[string]interface{}) error {
+	for _, fk := range a.fieldKeys(dst.Type()) {
+		v, ok := m[fk.key]
+		if !ok && a.dt == typeJSON {
+			// case insensitive match like encoding/json
+			for k, vv := range m {
+				if strings.EqualFold(k, fk.key) {
+					v, ok = vv, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+
+		if err := a.assign(dst.Field(fk.index), v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unknownKeys adds the paths of the keys in v that are not decoded into typ to keys.
+// Maps and interface{} accept all keys.
+func (a *assigner) unknownKeys(typ reflect.Type, v interface{}, path string, keys map[string]struct{}) {
+	switch typ.Kind() {
+	case reflect.Ptr:
+		a.unknownKeys(typ.Elem(), v, path, keys)
+	case reflect.Slice:
+		s, ok := v.([]interface{})
+		if !ok {
+			return
+		}
+		for _, e := range s {
+			a.unknownKeys(typ.Elem(), e, path, keys)
+		}
+	case reflect.Struct:
+		m, ok := v.(map[string]interface{})
+		if !ok || !isPlainStruct(typ) {
+			return
+		}
+		fks := a.fieldKeys(typ)
+		for k, vv := range m {
+			i := a.findFieldKey(fks, k)
+			if i < 0 {
+				keys[joinPath(path, k)] = struct{}{}
+				continue
+			}
+			a.unknownKeys(typ.Field(fks[i].index).Type, vv, joinPath(path, k), keys)
+		}
+	}
+}
+
+// findFieldKey returns the index in fks of the key k, or -1 if not found.
+// Keys of JSON are matched case insensitively like assignStruct.
+func (a *assigner) findFieldKey(fks []fieldKey, k string) int {
+	for i, fk := range fks {
+		if fk.key == k {
+			return i
+		}
+	}
+	if a.dt != typeJSON {
+		return -1
+	}
+	for i, fk := range fks {
+		if strings.EqualFold(fk.key, k) {
+			return i
+		}
+	}
+	return -1
+}
+
+// plain returns v for interface{} values. JSON numbers are float64 like encoding/json.
+func (a *assigner) plain(v interface{}) interface{} {
+	if a.dt != typeJSON {
+		return v
+	}
+
+	switch t := v.(type) {
+	case json.Number:
+		f, _ := t.Float64()
+		return f
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(t))
+		for k, e := range t {
+			m[k] = a.plain(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(t))
+		for i, e := range t {
+			s[i] = a.plain(e)
+		}
+		return s
+	}
+	return v
+}
+
+// isPlainStruct reports whether typ is a struct built by DynamicStruct (not time.Time and so on).
+func isPlainStruct(typ reflect.Type) bool {
+	if typ.Name() != "" {
+		return false
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		if typ.Field(i).PkgPath != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func toInt64(v interface{}) (int64, bool) {
+	switch t := v.(type) {
+	case json.Number:
+		i, err := t.Int64()
+		return i, err == nil
+	case int:
+		return int64(t), true
+	case int64:
+		return t, true
+	case uint64:
+		return int64(t), t <= math.MaxInt64
+	}
+	return 0, false
+}
+
+func toUint64(v interface{}) (uint64, bool) {
+	switch t := v.(type) {
+	case json.Number:
+		u, err := strconv.ParseUint(t.String(), 10, 64)
+		return u, err == nil
+	case int:
+		return uint64(t), t >= 0
+	case int64:
+		return uint64(t), t >= 0
+	case uint64:
+		return t, true
+	}
+	return 0, false
+}
+
+func toFloat64(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case json.Number:
+		f, err := t.Float64()
+		return f, err == nil
+	case float64:
+		return t, true
+	case int:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case uint64:
+		return float64(t), true
+	}
+	return 0, false
+}
@@ -197,3 +197,43 @@ func BenchmarkYAMLToGetter_arrayYAML_nest(b *testing.B) {
 		_, _ = YAMLToGetter(arrayYAML, true)
 	}
 }
+
+func BenchmarkDecodeToSlice_arrayJSON_nest(b *testing.B) {
+	d, _ := FromJSON(arrayJSON)
+	ds, _ := d.DynamicStruct(true, true)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = d.DecodeToSlice(ds)
+	}
+}
+
+func BenchmarkDecodeToSlice_arrayYAML_nest(b *testing.B) {
+	d, _ := FromYAML(arrayYAML)
+	ds, _ := d.DynamicStruct(true, true)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = d.DecodeToSlice(ds)
+	}
+}
+
+func BenchmarkDecodeToSlice_singleTOML_nest(b *testing.B) {
+	d, _ := FromTOML(singleTOML)
+	ds, _ := d.DynamicStruct(true, true)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = d.DecodeToSlice(ds)
+	}
+}
+
+func BenchmarkDecodeToSlice_singleXML(b *testing.B) {
+	d, _ := FromXML(singleXML)
+	ds, _ := d.DynamicStruct(true, true)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = d.DecodeToSlice(ds)
+	}
+}
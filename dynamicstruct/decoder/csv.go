@@ -0,0 +1,328 @@
+package decoder
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goldeneggg/structil"
+	"github.com/goldeneggg/structil/dynamicstruct"
+)
+
+const (
+	csvTagKey            = "csv"
+	defaultCSVSampleSize = 100
+)
+
+var defaultCSVTimeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"}
+
+// CSVOptions is the options for FromCSV.
+type CSVOptions struct {
+	// Comma is the field delimiter. The default is ','.
+	Comma rune
+
+	// SampleSize is the number of rows used for inferring the column types. The default is 100.
+	// If SampleSize is negative, all rows are used.
+	SampleSize int
+
+	// TimeLayouts is the layouts for time.Time columns.
+	// The default is time.RFC3339, "2006-01-02 15:04:05" and "2006-01-02".
+	TimeLayouts []string
+
+	// FieldName converts the headers to the field names. The default is GoFieldName.
+	FieldName FieldNameFunc
+}
+
+// csvKind is the inferred type of a column.
+type csvKind int
+
+const (
+	csvString csvKind = 0
+	csvBool   csvKind = 1 << (iota - 1)
+	csvInt
+	csvFloat
+	csvTime
+)
+
+type csvColumn struct {
+	cands    csvKind // candidate kinds for all values in the sample
+	layout   string  // time layout
+	nullable bool    // the sample has empty values
+	hasValue bool    // the sample has non-empty values
+}
+
+func (c *csvColumn) kind() csvKind {
+	if !c.hasValue {
+		return csvString
+	}
+
+	for _, k := range []csvKind{csvBool, csvInt, csvFloat, csvTime} {
+		if c.cands&k != 0 {
+			return k
+		}
+	}
+	return csvString
+}
+
+func (c *csvColumn) typ() reflect.Type {
+	var t reflect.Type
+	switch c.kind() {
+	case csvBool:
+		t = reflect.TypeOf(false)
+	case csvInt:
+		t = reflect.TypeOf(int64(0))
+	case csvFloat:
+		t = reflect.TypeOf(float64(0))
+	case csvTime:
+		t = reflect.TypeOf(time.Time{})
+	default:
+		// an empty string is a valid string, so string columns are not nullable
+		return reflect.TypeOf("")
+	}
+
+	if c.nullable {
+		return reflect.PtrTo(t)
+	}
+	return t
+}
+
+func (c *csvColumn) infer(s string, layouts []string) {
+	if s == "" {
+		c.nullable = true
+		return
+	}
+	if !c.hasValue {
+		c.hasValue = true
+		c.cands = csvBool | csvInt | csvFloat | csvTime
+	}
+
+	if c.cands&csvBool != 0 && !strings.EqualFold(s, "true") && !strings.EqualFold(s, "false") {
+		c.cands &^= csvBool
+	}
+	if _, err := strconv.ParseInt(s, 10, 64); c.cands&csvInt != 0 && err != nil {
+		c.cands &^= csvInt
+	}
+	if _, err := strconv.ParseFloat(s, 64); c.cands&csvFloat != 0 && err != nil {
+		c.cands &^= csvFloat
+	}
+	if c.cands&csvTime != 0 {
+		// all values must be parsed by the layout for the first value
+		if c.layout == "" {
+			for _, l := range layouts {
+				if _, err := time.Parse(l, s); err == nil {
+					c.layout = l
+					break
+				}
+			}
+		}
+		if _, err := time.Parse(c.layout, s); c.layout == "" || err != nil {
+			c.cands &^= csvTime
+		}
+	}
+}
+
+// CSVDecoder is the decoder for CSV that infers a DynamicStruct of rows.
+type CSVDecoder struct {
+	r      *csv.Reader
+	header []string
+	cols   []csvColumn
+	fields []int      // field indexes of the columns
+	sample [][]string // rows read for the inference and not decoded yet
+	n      int        // number of the decoded rows
+	ds     *dynamicstruct.DynamicStruct
+}
+
+// FromCSV returns a CSVDecoder for CSV data from r.
+// The header row is used for the field names and the `csv:"..."` tags.
+// The field names are converted by opts.FieldName, "ColumnN" is used for empty headers,
+// and the number suffix is added to the duplicated names (e.g. "A" and "A2" for "a,a").
+// The column types are inferred over the sample rows as bool, int64, float64, time.Time or string,
+// and the columns that have empty values in the sample are pointers (except string columns).
+func FromCSV(r io.Reader, opts CSVOptions) (*CSVDecoder, error) {
+	if opts.SampleSize == 0 {
+		opts.SampleSize = defaultCSVSampleSize
+	}
+	if len(opts.TimeLayouts) == 0 {
+		opts.TimeLayouts = defaultCSVTimeLayouts
+	}
+	if opts.FieldName == nil {
+		opts.FieldName = GoFieldName
+	}
+
+	cr := csv.NewReader(r)
+	if opts.Comma != 0 {
+		cr.Comma = opts.Comma
+	}
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("header row is not found in CSV")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fail to read CSV header: %w", err)
+	}
+	header[0] = strings.TrimPrefix(header[0], "\ufeff") // UTF-8 BOM
+
+	d := &CSVDecoder{
+		r:      cr,
+		header: header,
+		cols:   make([]csvColumn, len(header)),
+	}
+
+	for opts.SampleSize < 0 || len(d.sample) < opts.SampleSize {
+		rec, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("fail to read CSV: %w", err)
+		}
+
+		for i, s := range rec {
+			d.cols[i].infer(s, opts.TimeLayouts)
+		}
+		d.sample = append(d.sample, rec)
+	}
+
+	if d.ds, err = d.build(opts.FieldName); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+func (d *CSVDecoder) build(fieldName FieldNameFunc) (*dynamicstruct.DynamicStruct, error) {
+	b := dynamicstruct.NewBuilder()
+	names := make([]string, len(d.header))
+	for i, h := range d.header {
+		name := fieldName(strings.TrimSpace(h))
+		if name == "" {
+			name = fmt.Sprintf("Column%d", i+1)
+		}
+		names[i] = uniqueFieldName(b.Exists, name)
+
+		tag := dynamicstruct.NewTag().Set(csvTagKey, h).String()
+		b = b.AddTypeWithTag(names[i], d.cols[i].typ(), tag)
+	}
+
+	ds, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	d.fields = make([]int, len(names))
+	for i, name := range names {
+		sf, _ := ds.FieldByName(name)
+		d.fields[i] = sf.Index[0]
+	}
+
+	return ds, nil
+}
+
+// Header returns the header row.
+func (d *CSVDecoder) Header() []string {
+	return d.header
+}
+
+// DynamicStruct returns the inferred DynamicStruct of rows.
+func (d *CSVDecoder) DynamicStruct() *dynamicstruct.DynamicStruct {
+	return d.ds
+}
+
+// Next decodes the next row and returns a structil.Getter of it.
+// io.EOF is returned if there are no more rows.
+func (d *CSVDecoder) Next() (*structil.Getter, error) {
+	v, err := d.next()
+	if err != nil {
+		return nil, err
+	}
+
+	return structil.NewGetter(v)
+}
+
+// DecodeToSlice decodes the all remaining rows into a new slice of the DynamicStruct
+// and returns the pointer to it (e.g. *[]*DynamicStruct).
+func (d *CSVDecoder) DecodeToSlice() (interface{}, error) {
+	sp := d.ds.NewSliceOfPtr(0, 0)
+	rv := reflect.ValueOf(sp).Elem()
+	for {
+		v, err := d.next()
+		if errors.Is(err, io.EOF) {
+			return sp, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		rv.Set(reflect.Append(rv, reflect.ValueOf(v)))
+	}
+}
+
+func (d *CSVDecoder) next() (interface{}, error) {
+	var rec []string
+	if len(d.sample) > 0 {
+		rec, d.sample = d.sample[0], d.sample[1:]
+	} else {
+		var err error
+		if rec, err = d.r.Read(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("fail to read CSV: %w", err)
+		}
+	}
+	d.n++
+
+	pv := reflect.New(d.ds.Type())
+	sv := pv.Elem()
+	for i, s := range rec {
+		if err := d.cols[i].set(sv.Field(d.fields[i]), s); err != nil {
+			return nil, fmt.Errorf("row %d, column %q: %w", d.n, d.header[i], err)
+		}
+	}
+
+	return pv.Interface(), nil
+}
+
+func (c *csvColumn) set(fv reflect.Value, s string) error {
+	if fv.Kind() == reflect.String {
+		fv.SetString(s)
+		return nil
+	}
+
+	if s == "" {
+		if c.nullable {
+			return nil
+		}
+		return fmt.Errorf("empty value for non-nullable %v", fv.Type())
+	}
+
+	if fv.Kind() == reflect.Ptr {
+		fv.Set(reflect.New(fv.Type().Elem()))
+		fv = fv.Elem()
+	}
+
+	var v interface{}
+	var err error
+	switch c.kind() {
+	case csvBool:
+		v, err = strconv.ParseBool(strings.ToLower(s))
+	case csvInt:
+		v, err = strconv.ParseInt(s, 10, 64)
+	case csvFloat:
+		v, err = strconv.ParseFloat(s, 64)
+	case csvTime:
+		v, err = time.Parse(c.layout, s)
+	}
+	if err != nil {
+		return fmt.Errorf("value %q is not %v: %w", s, fv.Type(), err)
+	}
+
+	fv.Set(reflect.ValueOf(v))
+	return nil
+}
@@ -0,0 +1,227 @@
+package decoder_test
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	. "github.com/goldeneggg/structil/dynamicstruct/decoder"
+)
+
+const csvUsers = "\ufeffid,name,score,is_admin,created_at,age,\n" +
+	"1,Alice,9.5,true,2021-01-02,20,x\n" +
+	"2,Bob,8,FALSE,2021-02-03,,y\n" +
+	"3,\"Carol, Jr.\",7.25,false,2021-03-04,30,z\n"
+
+func TestFromCSV(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		data           string
+		opts           CSVOptions
+		wantDefinition string
+		wantNum        int
+		wantErrorNew   bool
+		wantErrorRows  bool
+	}{
+		{
+			name: "Users",
+			data: csvUsers,
+			wantDefinition: `type DynamicStruct struct {
+	Age *int64 ` + "`csv:\"age\"`" + `
+	Column7 string ` + "`csv:\"\"`" + `
+	CreatedAt time.Time ` + "`csv:\"created_at\"`" + `
+	ID int64 ` + "`csv:\"id\"`" + `
+	IsAdmin bool ` + "`csv:\"is_admin\"`" + `
+	Name string ` + "`csv:\"name\"`" + `
+	Score float64 ` + "`csv:\"score\"`" + `
+}`,
+			wantNum: 3,
+		},
+		{
+			name: "TSV",
+			data: "a\tb\n1\t2\n",
+			opts: CSVOptions{Comma: '\t'},
+			wantDefinition: `type DynamicStruct struct {
+	A int64 ` + "`csv:\"a\"`" + `
+	B int64 ` + "`csv:\"b\"`" + `
+}`,
+			wantNum: 1,
+		},
+		{
+			name: "DuplicateHeaderAndNoRows",
+			data: "a,a\n",
+			wantDefinition: `type DynamicStruct struct {
+	A string ` + "`csv:\"a\"`" + `
+	A2 string ` + "`csv:\"a\"`" + `
+}`,
+			wantNum: 0,
+		},
+		{
+			name: "DuplicateNumericAndSymbolHeaders",
+			data: "a,a3,a,2020,@id,user_id\n1,2,3,4,5,6\n",
+			wantDefinition: `type DynamicStruct struct {
+	A int64 ` + "`csv:\"a\"`" + `
+	A2 int64 ` + "`csv:\"a\"`" + `
+	A3 int64 ` + "`csv:\"a3\"`" + `
+	F2020 int64 ` + "`csv:\"2020\"`" + `
+	ID int64 ` + "`csv:\"@id\"`" + `
+	UserID int64 ` + "`csv:\"user_id\"`" + `
+}`,
+			wantNum: 1,
+		},
+		{
+			name: "CamelCaseFieldName",
+			data: "user_id,api_url\n1,x\n",
+			opts: CSVOptions{FieldName: CamelCaseFieldName},
+			wantDefinition: `type DynamicStruct struct {
+	ApiUrl string ` + "`csv:\"api_url\"`" + `
+	UserId int64 ` + "`csv:\"user_id\"`" + `
+}`,
+			wantNum: 1,
+		},
+		{
+			name: "OutOfSample",
+			data: "a\n1\n2\nx\n",
+			opts: CSVOptions{SampleSize: 2},
+			wantDefinition: `type DynamicStruct struct {
+	A int64 ` + "`csv:\"a\"`" + `
+}`,
+			wantErrorRows: true,
+		},
+		{
+			name: "AllRowsSample",
+			data: "a\n1\n2\nx\n",
+			opts: CSVOptions{SampleSize: -1},
+			wantDefinition: `type DynamicStruct struct {
+	A string ` + "`csv:\"a\"`" + `
+}`,
+			wantNum: 3,
+		},
+		{
+			name:         "Empty",
+			data:         "",
+			wantErrorNew: true,
+		},
+		{
+			name:         "WrongNumberOfFields",
+			data:         "a,b\n1\n",
+			wantErrorNew: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dec, err := FromCSV(strings.NewReader(tt.data), tt.opts)
+			if err != nil {
+				if !tt.wantErrorNew {
+					t.Fatalf("unexpected error is returned from FromCSV: %v", err)
+				}
+				return
+			} else if tt.wantErrorNew {
+				t.Fatalf("error is expected but it does not occur from FromCSV. data: %q", tt.data)
+			}
+
+			if d := cmp.Diff(dec.DynamicStruct().Definition(), tt.wantDefinition); d != "" {
+				t.Fatalf("mismatch Definition: (-got +want)\n%s", d)
+			}
+
+			sp, err := dec.DecodeToSlice()
+			if err != nil {
+				if !tt.wantErrorRows {
+					t.Fatalf("unexpected error is returned from DecodeToSlice: %v", err)
+				}
+				return
+			} else if tt.wantErrorRows {
+				t.Fatalf("error is expected but it does not occur from DecodeToSlice. data: %q", tt.data)
+			}
+
+			if n := reflect.ValueOf(sp).Elem().Len(); n != tt.wantNum {
+				t.Errorf("unexpected number of rows. got: %d, want: %d", n, tt.wantNum)
+			}
+		})
+	}
+}
+
+func TestCSVDecoderNext(t *testing.T) {
+	t.Parallel()
+
+	dec, err := FromCSV(strings.NewReader(csvUsers), CSVOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error is returned from FromCSV: %v", err)
+	}
+
+	var names []string
+	var ages []interface{}
+	for {
+		g, err := dec.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error is returned from Next: %v", err)
+		}
+
+		name, _ := g.String("Name")
+		names = append(names, name)
+		age, _ := g.Get("Age")
+		ages = append(ages, age)
+
+		if id, _ := g.Int64("ID"); id == 1 {
+			if ct, _ := g.Get("CreatedAt"); !ct.(time.Time).Equal(time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)) {
+				t.Errorf("unexpected CreatedAt: %v", ct)
+			}
+		}
+	}
+
+	if d := cmp.Diff(names, []string{"Alice", "Bob", "Carol, Jr."}); d != "" {
+		t.Errorf("mismatch Name: (-got +want)\n%s", d)
+	}
+	if d := cmp.Diff(ages, []interface{}{int64(20), nil, int64(30)}); d != "" {
+		t.Errorf("mismatch Age: (-got +want)\n%s", d)
+	}
+
+	// Age is not nullable if the sample (only the first row) has no empty value
+	dec, err = FromCSV(strings.NewReader(csvUsers), CSVOptions{SampleSize: 1})
+	if err != nil {
+		t.Fatalf("unexpected error is returned from FromCSV: %v", err)
+	}
+	if _, err := dec.Next(); err != nil {
+		t.Fatalf("unexpected error is returned from Next: %v", err)
+	}
+	if _, err := dec.Next(); err == nil || !strings.Contains(err.Error(), `row 2, column "age"`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCSVDecoderNextHeaderNames(t *testing.T) {
+	t.Parallel()
+
+	dec, err := FromCSV(strings.NewReader("a,a3,a,2020,@id\n1,2,3,4,5\n"), CSVOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error is returned from FromCSV: %v", err)
+	}
+
+	g, err := dec.Next()
+	if err != nil {
+		t.Fatalf("unexpected error is returned from Next: %v", err)
+	}
+
+	got := make(map[string]int64)
+	for _, name := range g.Names() {
+		got[name], _ = g.Int64(name)
+	}
+	want := map[string]int64{"A": 1, "A3": 2, "A2": 3, "F2020": 4, "ID": 5}
+	if d := cmp.Diff(got, want); d != "" {
+		t.Errorf("mismatch values: (-got +want)\n%s", d)
+	}
+}
@@ -1,9 +1,14 @@
 package decoder
 
 import (
+	"bytes"
 	"encoding/json"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 
+	"github.com/pelletier/go-toml/v2"
 	"gopkg.in/yaml.v3"
 )
 
@@ -18,16 +23,13 @@ const (
 	// TypeYAML is the type sign of YAML
 	typeYAML
 
-	// FIXME: futures as follows
+	// TypeTOML is the type sign of TOML
+	typeTOML
 
 	// TypeXML is the type sign of XML
-	// TypeXML
-
-	// TypeTOML is the type sign of TOML
-	// TypeTOML
+	typeXML
 
-	// TypeCSV is the type sign of CSV
-	// TypeCSV
+	// Note: CSV is not a dataType because it is decoded by CSVDecoder row by row
 
 	end // end of iota
 )
@@ -35,6 +37,8 @@ const (
 var formats = [...]string{
 	typeJSON: "json",
 	typeYAML: "yaml",
+	typeTOML: "toml",
+	typeXML:  "xml",
 }
 
 func (dt dataType) string() string {
@@ -45,6 +49,15 @@ func (dt dataType) string() string {
 }
 
 func (dt dataType) unmarshal(data []byte) (interface{}, error) {
+	// Note: encoding/xml can not unmarshal to interface{}
+	if dt == typeXML {
+		return unmarshalXML(data)
+	}
+	// Note: JSON numbers are json.Number for inferring int64, uint64 and float64
+	if dt == typeJSON {
+		return unmarshalJSONWithNumber(data)
+	}
+
 	var intf interface{}
 	err := dt.unmarshalWithIPtr(data, &intf)
 	return intf, err
@@ -60,6 +73,12 @@ func (dt dataType) unmarshalWithIPtr(data []byte, iptr interface{}) error {
 	case typeYAML:
 		// Note: iptr should be "map[interface{}]interface{}" using gopkg.in/yaml.v2 package
 		err = yaml.Unmarshal(data, iptr)
+	case typeTOML:
+		// Note: integers are int64, and datetimes are time.Time or toml.LocalXxx
+		err = toml.Unmarshal(data, iptr)
+	case typeXML:
+		// Note: iptr should be a pointer to struct because encoding/xml can not unmarshal to maps
+		err = xml.Unmarshal(data, iptr)
 	default:
 		err = fmt.Errorf("invalid datatype for Unmarshal: %v", dt)
 	}
@@ -68,18 +87,42 @@ func (dt dataType) unmarshalWithIPtr(data []byte, iptr interface{}) error {
 }
 
 // TODO: add tests and examples
-// func (dt dataType) marshal(v interface{}) (data []byte, err error) {
-func (dt dataType) marshal(m map[string]interface{}) (data []byte, err error) {
+func (dt dataType) marshal(v interface{}) (data []byte, err error) {
 	switch dt {
 	case typeJSON:
-		// Note: v is expected to be "map[string]interface{}"
-		data, err = json.Marshal(m)
+		// Note: v is expected to be "map[string]interface{}" or "[]interface{}"
+		data, err = json.Marshal(v)
 	case typeYAML:
 		// Note: v is expected to be converted from "map[interface{}]interface{}" to "map[string]interface{}"
-		data, err = yaml.Marshal(m)
+		data, err = yaml.Marshal(v)
+	case typeTOML:
+		// Note: v is expected to be "map[string]interface{}" because TOML document must be a table
+		data, err = toml.Marshal(v)
+	case typeXML:
+		// Note: v is expected to be "map[string]interface{}" converted by unmarshalXML
+		data, err = marshalXML(v)
 	default:
 		err = fmt.Errorf("invalid datatype for Marshal: %v", dt)
 	}
 
 	return
 }
+
+func unmarshalJSONWithNumber(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var intf interface{}
+	if err := dec.Decode(&intf); err != nil {
+		return nil, err
+	}
+	// the same as json.Unmarshal, data must have only one value
+	tok, err := dec.Token()
+	if err == nil {
+		return nil, fmt.Errorf("invalid data after top-level value: unexpected token %v", tok)
+	} else if !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("invalid data after top-level value: %w", err)
+	}
+
+	return intf, nil
+}
@@ -1,36 +1,71 @@
 package decoder
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
 
-	"github.com/iancoleman/strcase"
+	"github.com/pelletier/go-toml/v2"
 
 	"github.com/goldeneggg/structil"
 	"github.com/goldeneggg/structil/dynamicstruct"
 )
 
-// Decoder is the struct that decodes some marshaled data like JSON and YAML.
+// Decoder is the struct that decodes some marshaled data like JSON, YAML, TOML and XML.
 type Decoder struct {
 	dt        dataType
 	orgData   []byte                 // original data
 	orgIntf   interface{}            // unmarshaled interface from original data
 	strKeyMap map[string]interface{} // string key map for decoding to DymanicStruct
 	ds        *dynamicstruct.DynamicStruct
-	dsi       interface{} // unmarshaled result from data to DynamicStruct
+	dsi       interface{}                  // unmarshaled result from data to DynamicStruct
+	numKinds  map[string]numKind           // widened kinds of JSON numbers by field paths of all documents
+	emptyElem reflect.Type                 // element type for empty arrays
+	elemTypes map[string]reflect.Type      // widened element types of non-empty arrays by field paths of all documents
+	keys      map[string]keySet            // keys of objects by paths of all documents
+	names     map[string]map[string]string // field names of keys by paths of objects
+	sampleN   int                          // number of array elements for inferring the element struct (all elements if 0)
+	fieldName FieldNameFunc                // converts keys to field names
 }
 
+var (
+	interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+	strKeyMapType = reflect.TypeOf(map[string]interface{}{})
+)
+
+// mergeOpts is the options to merge the DynamicStructs inferred from the elements of arrays and documents.
+var mergeOpts = dynamicstruct.MergeOptions{Policy: dynamicstruct.PolicyWiden, Optional: true}
+
 func newDecoder(data []byte, dt dataType) (*Decoder, error) {
 	unm, err := dt.unmarshal(data)
 	if err != nil {
 		return nil, err
 	}
 
+	return newDecoderFromIntf(data, unm, dt)
+}
+
+// newDecoderFromIntf returns a Decoder for unm that is unmarshaled from data.
+func newDecoderFromIntf(data []byte, unm interface{}, dt dataType) (*Decoder, error) {
 	dec := &Decoder{
 		dt:        dt,
 		orgData:   data,
 		orgIntf:   unm,
 		strKeyMap: make(map[string]interface{}),
+		numKinds:  make(map[string]numKind),
+		emptyElem: interfaceType,
+		fieldName: GoFieldName,
 	}
+	collectNumKinds(unm, "", dec.numKinds)
+	dec.elemTypes = make(map[string]reflect.Type)
+	dec.collectElemTypes(unm, "")
+	dec.keys = make(map[string]keySet)
+	dec.names = make(map[string]map[string]string)
+	dec.collectKeys(unm, "")
 
 	switch t := dec.orgIntf.(type) {
 	case map[string]interface{}:
@@ -42,8 +77,8 @@ func newDecoder(data []byte, dt dataType) (*Decoder, error) {
 	// 	dec.strKeyMap = toStringKeyMap(t)
 	case []interface{}:
 		if len(t) > 0 {
-			// The items in the array must be same for all elements.
-			// So the first element is used to process
+			// strKeyMap is only the first element, which is used to decode to a single Getter.
+			// The DynamicStruct is built from all (or sampled) elements merged by toDsFromMaps.
 			switch tt := t[0].(type) {
 			case map[string]interface{}:
 				dec.strKeyMap = tt
@@ -62,6 +97,9 @@ func newDecoder(data []byte, dt dataType) (*Decoder, error) {
 }
 
 // FromJSON returns a concrete Decoder for JSON.
+// JSON numbers are decoded as int64, uint64 (over math.MaxInt64) or float64 per field.
+// If the numbers of a field are different kinds in array elements (or documents of a top-level array),
+// the kind is widened (e.g. int64 and float64 to float64).
 func FromJSON(data []byte) (*Decoder, error) {
 	return newDecoder(data, typeJSON)
 }
@@ -71,10 +109,24 @@ func FromYAML(data []byte) (*Decoder, error) {
 	return newDecoder(data, typeYAML)
 }
 
+// FromTOML returns a concrete Decoder for TOML.
+// TOML integers are decoded as int64, datetimes as time.Time (or toml.LocalDate, toml.LocalDateTime and toml.LocalTime),
+// tables and inline tables as nested DynamicStructs and arrays of tables as DynamicStruct slices.
+func FromTOML(data []byte) (*Decoder, error) {
+	return newDecoder(data, typeTOML)
+}
+
 // FromXML returns a concrete Decoder for XML.
-// FIXME: This function is still a future candidate (returned error now)
+// The fields of the root element are decoded as follows:
+//   - child elements are string fields, or nested DynamicStructs if they have attributes or child elements
+//   - repeated elements are slices
+//   - attributes are string fields with `xml:"name,attr"` tags
+//   - text content of elements with attributes or child elements is "Text" field with `xml:",chardata"` tag
+//
+// XML is always decoded with nest and useTag, because encoding/xml can not decode to maps
+// and can not find attributes without tags.
 func FromXML(data []byte) (*Decoder, error) {
-	return newDecoder(data, end) // FIXME: "end" is provisional type
+	return newDecoder(data, typeXML)
 }
 
 // JSONToGetter returns a structil.Getter with a decoded JSON via DynamicStruct.
@@ -97,6 +149,71 @@ func YAMLToGetter(data []byte, nest bool) (*structil.Getter, error) {
 	return d.dsToGetter(nest)
 }
 
+// TOMLToGetter returns a structil.Getter with a decoded TOML via DynamicStruct.
+func TOMLToGetter(data []byte, nest bool) (*structil.Getter, error) {
+	d, err := FromTOML(data)
+	if err != nil {
+		return nil, fmt.Errorf("fail to TOMLToGetter: %w", err)
+	}
+
+	return d.dsToGetter(nest)
+}
+
+// XMLToGetter returns a structil.Getter with a decoded XML via DynamicStruct.
+func XMLToGetter(data []byte) (*structil.Getter, error) {
+	d, err := FromXML(data)
+	if err != nil {
+		return nil, fmt.Errorf("fail to XMLToGetter: %w", err)
+	}
+
+	return d.dsToGetter(true)
+}
+
+// JSONToGetters returns structil.Getters with a decoded JSON via DynamicStruct.
+// If data is a top-level array, all elements are decoded into a typed slice and a Getter per element is returned.
+func JSONToGetters(data []byte, nest bool) ([]*structil.Getter, error) {
+	d, err := FromJSON(data)
+	if err != nil {
+		return nil, fmt.Errorf("fail to JSONToGetters: %w", err)
+	}
+
+	return d.dsToGetters(nest)
+}
+
+// YAMLToGetters returns structil.Getters with a decoded YAML via DynamicStruct.
+// If data is a top-level array, all elements are decoded into a typed slice and a Getter per element is returned.
+func YAMLToGetters(data []byte, nest bool) ([]*structil.Getter, error) {
+	d, err := FromYAML(data)
+	if err != nil {
+		return nil, fmt.Errorf("fail to YAMLToGetters: %w", err)
+	}
+
+	return d.dsToGetters(nest)
+}
+
+func (d *Decoder) dsToGetters(nest bool) ([]*structil.Getter, error) {
+	ds, err := d.DynamicStruct(nest, true)
+	if err != nil {
+		return nil, err
+	}
+
+	sp, err := d.DecodeToSlice(ds)
+	if err != nil {
+		return nil, err
+	}
+
+	rv := reflect.ValueOf(sp).Elem()
+	gs := make([]*structil.Getter, rv.Len())
+	for i := 0; i < rv.Len(); i++ {
+		gs[i], err = structil.NewGetter(rv.Index(i).Interface())
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return gs, nil
+}
+
 func (d *Decoder) dsToGetter(nest bool) (*structil.Getter, error) {
 	ds, err := d.DynamicStruct(nest, true)
 	if err != nil {
@@ -116,32 +233,108 @@ func (d *Decoder) decodeToDynamicStruct(ds *dynamicstruct.DynamicStruct) (interf
 	// So ds.NewInterface() returns a struct *pointer*
 	d.dsi = ds.NewInterface()
 
-	// must use "d.strKeyMap" (not "d.orgIntf"). because key of "d.orgIntf" is not string but interface{}
-	data, err := d.dt.marshal(d.strKeyMap)
-	if err != nil {
-		return nil, fmt.Errorf("fail to d.dt.marshal: %w", err)
-	}
-
 	// must use "d.dsi" (not "&d.dsi"). because "d.dsi" is pointer
 	// if use "&.d.dsi", unmarshal result is not struct but map[interface{}]interface when dt is YAML
-	if err := d.dt.unmarshalWithIPtr(data, d.dsi); err != nil {
-		return nil, fmt.Errorf("fail to d.dt.unmarshalWithIPtr: %w", err)
+	if err := d.decodeTo(d.strKeyMap, d.dsi); err != nil {
+		return nil, err
 	}
 
 	return d.dsi, nil
 }
 
+// DecodeToSlice decodes the original data into a new slice of ds and returns the pointer to it (e.g. *[]*DynamicStruct).
+// If the original data is a top-level array, all elements are decoded.
+// Otherwise the returned slice has only one element.
+func (d *Decoder) DecodeToSlice(ds *dynamicstruct.DynamicStruct) (interface{}, error) {
+	sp := ds.NewSliceOfPtr(0, 0)
+
+	t, ok := d.orgIntf.([]interface{})
+	if !ok {
+		// some formats (e.g. TOML) can not marshal a top-level array, so decode an element and append it
+		ep := reflect.New(ds.Type())
+		if err := d.decodeTo(d.strKeyMap, ep.Interface()); err != nil {
+			return nil, err
+		}
+		rv := reflect.ValueOf(sp).Elem()
+		rv.Set(reflect.Append(rv, ep))
+		return sp, nil
+	}
+
+	if err := d.decodeTo(t, sp); err != nil {
+		return nil, err
+	}
+
+	return sp, nil
+}
+
+// decodeTo decodes v into iptr.
+// v is assigned directly, or decoded by the marshal and unmarshal round trip if v has types that can not be assigned.
+func (d *Decoder) decodeTo(v interface{}, iptr interface{}) error {
+	a := &assigner{dt: d.dt}
+	rv := reflect.ValueOf(iptr).Elem()
+	err := a.assign(rv, v)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, errFallback) {
+		return err
+	}
+	rv.Set(reflect.Zero(rv.Type()))
+
+	data, err := d.dt.marshal(v)
+	if err != nil {
+		return fmt.Errorf("fail to d.dt.marshal: %w", err)
+	}
+
+	if err := d.dt.unmarshalWithIPtr(data, iptr); err != nil {
+		return fmt.Errorf("fail to d.dt.unmarshalWithIPtr: %w", err)
+	}
+
+	return nil
+}
+
+// SetEmptyArrayElemType sets the element type of fields for empty arrays. The default is interface{} (e.g. "[]interface{}").
+func (d *Decoder) SetEmptyArrayElemType(typ reflect.Type) *Decoder {
+	d.emptyElem = typ
+	return d
+}
+
+// SetArraySampleSize sets the number of elements of arrays of objects used for inferring the element struct.
+// If n is 0 or less (the default), all elements are used.
+func (d *Decoder) SetArraySampleSize(n int) *Decoder {
+	d.sampleN = n
+	return d
+}
+
+// SetFieldNameFunc sets the function that converts keys to field names. The default is GoFieldName.
+// The keys of all objects at the same path (e.g. the elements of an array) are processed in sorted order,
+// and if some keys are converted to the same name, the number suffix is added to the names of the latter keys
+// (e.g. "FooBar" for "fooBar" and "FooBar2" for "foo_bar").
+// The original keys are always kept in the tags.
+func (d *Decoder) SetFieldNameFunc(f FieldNameFunc) *Decoder {
+	d.fieldName = f
+	return d
+}
+
 // OrgData returns an original data as []byte.
 func (d *Decoder) OrgData() []byte {
 	return d.orgData
 }
 
 // DynamicStruct returns a decoded DynamicStruct with unmarshaling data to DynamicStruct interface.
+// nest and useTag are always true for XML.
 func (d *Decoder) DynamicStruct(nest bool, useTag bool) (*dynamicstruct.DynamicStruct, error) {
 	var err error
 
-	// d.ds, err = d.toDs(d.orgIntf, nest, useTag)
-	d.ds, err = d.toDs(d.strKeyMap, nest, useTag)
+	if d.dt == typeXML {
+		nest, useTag = true, true
+	}
+
+	if t, ok := d.orgIntf.([]interface{}); ok && len(t) > 0 {
+		d.ds, err = d.toDsFromMaps(t, "", nest, useTag)
+	} else {
+		d.ds, err = d.toDs(d.strKeyMap, nest, useTag)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -152,32 +345,40 @@ func (d *Decoder) DynamicStruct(nest bool, useTag bool) (*dynamicstruct.DynamicS
 func (d *Decoder) toDs(i interface{}, nest bool, useTag bool) (*dynamicstruct.DynamicStruct, error) {
 	switch t := i.(type) {
 	case map[string]interface{}:
-		return d.toDsFromStringMap(t, nest, useTag)
+		return d.toDsFromStringMap(t, "", nest, useTag)
 	}
 
 	return nil, fmt.Errorf("unsupported type [%T] for toDs", i)
 }
 
-func (d *Decoder) toDsFromStringMap(m map[string]interface{}, nest bool, useTag bool) (*dynamicstruct.DynamicStruct, error) {
+func (d *Decoder) toDsFromStringMap(m map[string]interface{}, path string, nest bool, useTag bool) (*dynamicstruct.DynamicStruct, error) {
 	var tag, name string
 	var err error
 	b := dynamicstruct.NewBuilder()
+	names := d.fieldNames(m, path)
 
-	for k, v := range m {
-		// TODO: apply initialisms theories. See: https://github.com/golang/go/wiki/CodeReviewComments#initialisms
-		//   (and more golint theories validations)
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		v := m[k]
 
-		// TODO: add "omitempty"? (e.g. when key is missing, type should be a pointer and have "omitempty")
 		// TODO: add ",string", ",boolean" extra options?
 		// See: https://golang.org/pkg/encoding/json/#Marshal
 		// See: https://m-zajac.github.io/json2go/
-		if useTag {
-			tag = fmt.Sprintf(`%s:"%s"`, d.dt.string(), k)
+		name = names[k]
+		tagName, tagOpts := k, []string(nil)
+		if d.dt == typeXML {
+			_, tagName, tagOpts = xmlFieldName(d.keys[path], k, d.fieldName)
 		}
 
-		// FIXME: the first character of k should be only alpha-numeric
-		// "@" や "/" は置換対応が必要かも
-		name = strcase.ToCamel(k)
+		if useTag {
+			tag = dynamicstruct.NewTag().Set(d.dt.string(), tagName, tagOpts...).String()
+		}
+		p := joinPath(path, k)
 
 		// See: https://golang.org/pkg/encoding/json/#Unmarshal
 		switch value := v.(type) {
@@ -187,37 +388,31 @@ func (d *Decoder) toDsFromStringMap(m map[string]interface{}, nest bool, useTag
 			b = b.AddFloat64WithTag(name, tag)
 		case string:
 			b = b.AddStringWithTag(name, tag)
+		case json.Number:
+			b = b.AddTypeWithTag(name, d.numKinds[p].typ(), tag)
 		case []interface{}:
-			if len(value) > 0 {
-				switch vv := value[0].(type) {
-				case map[string]interface{}:
-					b, err = d.addForStringMap(b, vv, true, tag, name, nest, useTag)
+			switch et := d.sliceType(value, p).Elem(); et {
+			case strKeyMapType:
+				if nest {
+					nds, err := d.toDsFromMaps(value, p, nest, useTag)
 					if err != nil {
 						return nil, err
 					}
-
-					if nest {
-						nds, err := d.toDsFromStringMap(vv, nest, useTag)
-						if err != nil {
-							return nil, err
-						}
-						b = b.AddDynamicStructSliceWithTag(name, nds, tag)
-					} else {
-						b = b.AddSliceWithTag(name, interface{}(vv), tag)
-					}
-				default:
-					// FIXME: 配列要素を全て "interface{}" にキャストしているが、型を明示したい
-					b = b.AddSliceWithTag(name, interface{}(vv), tag)
+					b = b.AddDynamicStructSliceWithTag(name, nds, tag)
+				} else {
+					b = b.AddSliceWithTag(name, value[0], tag)
 				}
+			default:
+				b = b.AddTypeWithTag(name, reflect.SliceOf(et), tag)
 			}
 		case map[string]interface{}:
-			b, err = d.addForStringMap(b, value, false, tag, name, nest, useTag)
+			b, err = d.addForStringMap(b, value, tag, name, p, nest, useTag)
 			if err != nil {
 				return nil, err
 			}
 
 			if nest {
-				nds, err := d.toDsFromStringMap(value, nest, useTag)
+				nds, err := d.toDsFromStringMap(value, p, nest, useTag)
 				if err != nil {
 					return nil, err
 				}
@@ -232,6 +427,9 @@ func (d *Decoder) toDsFromStringMap(m map[string]interface{}, nest bool, useTag
 		// YAML support
 		case int:
 			b = b.AddIntWithTag(name, tag)
+		// TOML (and YAML timestamps) support
+		case int64, time.Time, toml.LocalDate, toml.LocalDateTime, toml.LocalTime:
+			b = b.AddTypeWithTag(name, reflect.TypeOf(value), tag)
 		// YAML support
 		case nil:
 			b = b.AddInterfaceWithTag(name, false, tag)
@@ -243,26 +441,364 @@ func (d *Decoder) toDsFromStringMap(m map[string]interface{}, nest bool, useTag
 	return b.Build()
 }
 
+// toDsFromMaps returns the DynamicStruct that is merged from the DynamicStructs of the elements (objects) of the array value.
+// The keys that are missing in some elements are optional pointer fields with "omitempty",
+// and the conflicted types are widened (see dynamicstruct.Merge).
+func (d *Decoder) toDsFromMaps(value []interface{}, path string, nest bool, useTag bool) (*dynamicstruct.DynamicStruct, error) {
+	if d.sampleN > 0 && len(value) > d.sampleN {
+		value = value[:d.sampleN]
+	}
+
+	var ds *dynamicstruct.DynamicStruct
+	for _, e := range value {
+		m, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		eds, err := d.toDsFromStringMap(m, path, nest, useTag)
+		if err != nil {
+			return nil, err
+		}
+		if ds == nil {
+			ds = eds
+			continue
+		}
+
+		ds, err = d.merge(ds, eds)
+		if err != nil {
+			return nil, fmt.Errorf("fail to merge elements of %q: %w", path, err)
+		}
+	}
+
+	return ds, nil
+}
+
+// merge returns the DynamicStruct merged from a and b by mergeOpts.
+// The fields are merged by the field names that are assigned to the keys by fieldNames,
+// so an error is returned if the fields of different keys are merged.
+func (d *Decoder) merge(a *dynamicstruct.DynamicStruct, b *dynamicstruct.DynamicStruct) (*dynamicstruct.DynamicStruct, error) {
+	ds, conflicts, err := dynamicstruct.Merge(a, b, mergeOpts)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range conflicts {
+		if d.tagKey(c.LeftTag) != d.tagKey(c.RightTag) {
+			return nil, fmt.Errorf("fields of different keys are merged: %s", c)
+		}
+	}
+
+	return ds, nil
+}
+
+// tagKey returns the value of the tag for the data type without "omitempty" option that is added by merging.
+func (d *Decoder) tagKey(tag reflect.StructTag) string {
+	v := tag.Get(d.dt.string())
+	parts := strings.Split(v, ",")
+	key := parts[:1]
+	for _, opt := range parts[1:] {
+		if opt != "omitempty" {
+			key = append(key, opt)
+		}
+	}
+	return strings.Join(key, ",")
+}
+
+// fieldNames returns the field names of the keys of the objects of path.
+// The names are assigned to the keys of all objects of path (e.g. elements of an array) at once,
+// so that the same key always has the same name and the different keys never have the same name.
+// The keys are processed in sorted order, and the number suffix is added to the latter names of conflicted keys.
+func (d *Decoder) fieldNames(m map[string]interface{}, path string) map[string]string {
+	ks, ok := d.keys[path]
+	if !ok {
+		ks = make(keySet, len(m))
+		d.keys[path] = ks
+	}
+	for k := range m {
+		ks[k] = struct{}{}
+	}
+
+	names, ok := d.names[path]
+	if !ok {
+		names = make(map[string]string, len(ks))
+		d.names[path] = names
+	}
+
+	var newKeys []string
+	for k := range ks {
+		if _, ok := names[k]; !ok {
+			newKeys = append(newKeys, k)
+		}
+	}
+	if len(newKeys) == 0 {
+		return names
+	}
+	sort.Strings(newKeys)
+
+	used := make(map[string]bool, len(names))
+	for _, name := range names {
+		used[name] = true
+	}
+	exists := func(name string) bool {
+		return used[name]
+	}
+	for _, k := range newKeys {
+		name := d.fieldName(k)
+		if d.dt == typeXML {
+			name, _, _ = xmlFieldName(ks, k, d.fieldName)
+		}
+		names[k] = uniqueFieldName(exists, name)
+		used[names[k]] = true
+	}
+
+	return names
+}
+
+// collectKeys adds the keys of objects in v to d.keys by the dot separated paths.
+// The elements of arrays share the path of the array like collectNumKinds.
+func (d *Decoder) collectKeys(v interface{}, path string) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		ks, ok := d.keys[path]
+		if !ok {
+			ks = make(keySet, len(t))
+			d.keys[path] = ks
+		}
+		for k, vv := range t {
+			ks[k] = struct{}{}
+			d.collectKeys(vv, joinPath(path, k))
+		}
+	case []interface{}:
+		for _, e := range t {
+			d.collectKeys(e, path)
+		}
+	}
+}
+
+// sliceType returns the slice type for the array value inspected across all elements
+// (e.g. "[]string", "[]int64" and "[][]float64").
+func (d *Decoder) sliceType(value []interface{}, path string) reflect.Type {
+	et := d.elemType(value, path)
+	if et == nil {
+		et = d.emptyElemType(path)
+	}
+	return reflect.SliceOf(et)
+}
+
+// emptyElemType returns the element type for the empty array of path.
+// The empty array takes the element type of the non-empty arrays of the same path (e.g. in other elements of an array)
+// so that merging them does not widen the elements to pointers. Otherwise d.emptyElem is returned.
+func (d *Decoder) emptyElemType(path string) reflect.Type {
+	// an empty array of objects can not be inferred as a DynamicStruct
+	if et, ok := d.elemTypes[path]; ok && et != strKeyMapType {
+		return et
+	}
+	return d.emptyElem
+}
+
+// shareInference makes decs share the kinds of numbers, the element types of arrays and the field names of keys
+// inferred from all their documents, so that the DynamicStructs of the documents have the same types and names
+// as the elements of a single top-level array.
+func shareInference(decs []*Decoder) {
+	numKinds := make(map[string]numKind)
+	for _, d := range decs {
+		collectNumKinds(d.orgIntf, "", numKinds)
+	}
+
+	// the element types are collected after all kinds of numbers are collected
+	elemTypes := make(map[string]reflect.Type)
+	for _, d := range decs {
+		d.numKinds = numKinds
+		d.elemTypes = elemTypes
+	}
+	for _, d := range decs {
+		d.collectElemTypes(d.orgIntf, "")
+	}
+
+	keys := make(map[string]keySet)
+	names := make(map[string]map[string]string)
+	for _, d := range decs {
+		d.keys = keys
+		d.names = names
+		d.collectKeys(d.orgIntf, "")
+	}
+}
+
+// retypeNumbers returns the DynamicStruct whose number fields of ds are typed by the kinds of numbers of d.
+// This is used for ds merged from the documents that are inferred one by one (e.g. by StreamDecoder),
+// because dynamicstruct.Merge widens int64 and uint64 to float64 without knowing that the int64 numbers are non-negative.
+func (d *Decoder) retypeNumbers(ds *dynamicstruct.DynamicStruct) (*dynamicstruct.DynamicStruct, error) {
+	if len(d.numKinds) == 0 {
+		return ds, nil
+	}
+
+	a := &assigner{dt: d.dt}
+	var b *dynamicstruct.Builder
+	for _, fk := range a.fieldKeys(ds.Type()) {
+		f := ds.Field(fk.index)
+		typ := d.retypeNumber(a, f.Type, fk.key)
+		if typ == f.Type {
+			continue
+		}
+		if b == nil {
+			b = ds.ToBuilder()
+		}
+		b = b.AddTypeWithTag(f.Name, typ, string(f.Tag))
+	}
+	if b == nil {
+		return ds, nil
+	}
+
+	if ds.IsPtr() {
+		return b.Build()
+	}
+	return b.BuildNonPtr()
+}
+
+func (d *Decoder) retypeNumber(a *assigner, typ reflect.Type, path string) reflect.Type {
+	switch typ.Kind() {
+	case reflect.Ptr:
+		if et := d.retypeNumber(a, typ.Elem(), path); et != typ.Elem() {
+			return reflect.PtrTo(et)
+		}
+	case reflect.Slice:
+		if et := d.retypeNumber(a, typ.Elem(), path); et != typ.Elem() {
+			return reflect.SliceOf(et)
+		}
+	case reflect.Struct:
+		if !isPlainStruct(typ) {
+			return typ
+		}
+		fields := make([]reflect.StructField, typ.NumField())
+		changed := false
+		for i := range fields {
+			fields[i] = typ.Field(i)
+		}
+		for _, fk := range a.fieldKeys(typ) {
+			ft := d.retypeNumber(a, fields[fk.index].Type, joinPath(path, fk.key))
+			if ft != fields[fk.index].Type {
+				fields[fk.index].Type = ft
+				changed = true
+			}
+		}
+		if changed {
+			return reflect.StructOf(fields)
+		}
+	case reflect.Int64, reflect.Uint64, reflect.Float64:
+		if k := d.numKinds[path]; k != numNone {
+			return k.typ()
+		}
+	}
+
+	return typ
+}
+
+// collectElemTypes widens the element types of non-empty arrays in v by the dot separated paths.
+// The elements of arrays share the path of the array like collectNumKinds.
+func (d *Decoder) collectElemTypes(v interface{}, path string) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, vv := range t {
+			d.collectElemTypes(vv, joinPath(path, k))
+		}
+	case []interface{}:
+		if et := d.elemType(t, path); et != nil {
+			if cur, ok := d.elemTypes[path]; ok {
+				if et = widenType(cur, et); et == nil {
+					et = interfaceType
+				}
+			}
+			d.elemTypes[path] = et
+		}
+		for _, e := range t {
+			// the elements of nested arrays are inspected by elemType
+			if _, ok := e.([]interface{}); !ok {
+				d.collectElemTypes(e, path)
+			}
+		}
+	}
+}
+
+// elemType returns the element type of the array value, or nil if the array has no elements or only empty arrays.
+// Elements of different types are widened if they are numbers, otherwise the element type is interface{}.
+func (d *Decoder) elemType(value []interface{}, path string) reflect.Type {
+	var et reflect.Type
+	var hasEmpty bool
+	for _, e := range value {
+		var t reflect.Type
+		switch ev := e.(type) {
+		case nil:
+			t = interfaceType
+		case json.Number:
+			t = d.numKinds[path].typ()
+		case []interface{}:
+			it := d.elemType(ev, path)
+			if it == nil {
+				hasEmpty = true
+				continue
+			}
+			t = reflect.SliceOf(it)
+		default:
+			t = reflect.TypeOf(e)
+		}
+
+		if et == nil {
+			et = t
+		} else if et = widenType(et, t); et == nil {
+			return interfaceType
+		}
+	}
+
+	if hasEmpty {
+		if et == nil {
+			return reflect.SliceOf(d.emptyElem)
+		}
+		if et.Kind() != reflect.Slice {
+			return interfaceType
+		}
+	}
+	return et
+}
+
+// widenType returns the type that can hold the values of both a and b, or nil if there is no such type.
+func widenType(a reflect.Type, b reflect.Type) reflect.Type {
+	switch {
+	case a == b:
+		return a
+	case isNumberType(a) && isNumberType(b):
+		return float64Type
+	case a.Kind() == reflect.Slice && b.Kind() == reflect.Slice:
+		if et := widenType(a.Elem(), b.Elem()); et != nil {
+			return reflect.SliceOf(et)
+		}
+	}
+	return nil
+}
+
+func isNumberType(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int64, reflect.Uint64, reflect.Float64:
+		return true
+	}
+	return false
+}
+
 func (d *Decoder) addForStringMap(
 	b *dynamicstruct.Builder,
 	m map[string]interface{},
-	forSlice bool,
 	tag string,
 	name string,
+	path string,
 	nest bool,
 	useTag bool) (*dynamicstruct.Builder, error) {
 
-	// Note: forSlice judgement is top priority.
-	// FIXME: スライス内の要素のDynamicStruct変換サポート
-	// (currently, this causes a panic when field type is "array_struct")
 	if nest {
-		nds, err := d.toDsFromStringMap(m, nest, useTag)
+		nds, err := d.toDsFromStringMap(m, path, nest, useTag)
 		if err != nil {
 			return b, fmt.Errorf("addForStringMap nest = [%t], useTag = [%t]: %w", nest, useTag, err)
 		}
 		b = b.AddDynamicStructWithTag(name, nds, false, tag)
-	} else if forSlice {
-		b = b.AddSliceWithTag(name, interface{}(m), tag)
 	} else {
 		for kk := range m {
 			b = b.AddMapWithTag(name, kk, nil, tag)
@@ -1,17 +1,23 @@
 package decoder_test
 
 import (
+	"encoding/json"
+	"encoding/xml"
+	"reflect"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
 
 	"github.com/goldeneggg/structil"
+	"github.com/goldeneggg/structil/dynamicstruct"
 	. "github.com/goldeneggg/structil/dynamicstruct/decoder"
 )
 
 const (
 	typeJSON int = iota
 	typeYAML
+	typeTOML
 	typeXML
 )
 
@@ -171,7 +177,6 @@ array_struct_field:
       vvvv: vvv9999
 `)
 
-	//lint:ignore U1000 It's ok because this is for the future.
 	singleTOML = []byte(`
 string_field = "かきくけこ,"
 int_field = 45678
@@ -196,7 +201,6 @@ array_string_field = ["array_str_1", "array_str_2"]
   vvvv = "vvv3"
 `)
 
-	//lint:ignore U1000 It's ok because this is for the future.
 	singleXML = []byte(`
 <?xml version="1.0" encoding="UTF-8" ?>
 <root>
@@ -358,7 +362,7 @@ func TestDynamicStructJSON(t *testing.T) {
 			wantDefinition: `type DynamicStruct struct {
 	BoolField bool
 	Float32Field float64
-	IntField float64
+	IntField int64
 	NullField interface {}
 	StringField string
 }`,
@@ -435,14 +439,14 @@ func TestDynamicStructJSON(t *testing.T) {
 			wantNumF: 2,
 			wantDefinition: `type DynamicStruct struct {
 	ObjField struct {
-		Id float64
+		ID int64
 		Name string
 	}
 	StringField string
 }`,
 			fieldAndNestFields: map[string][]string{
 				"StringField": nil,
-				"ObjField":    {"Id", "Name"},
+				"ObjField":    {"ID", "Name"},
 			},
 		},
 		{
@@ -472,9 +476,9 @@ func TestDynamicStructJSON(t *testing.T) {
 			wantNumF: 2,
 			wantDefinition: `type DynamicStruct struct {
 	ObjField struct {
-		Id float64 ` + "`json:\"id\"`" + `
+		ID int64 ` + "`json:\"id\"`" + `
 		Name string ` + "`json:\"name\"`" + `
-		ObjArrayField []struct {
+		ObjArrayField []*struct {
 			K1 string ` + "`json:\"k1\"`" + `
 			K2 string ` + "`json:\"k2\"`" + `
 		} ` + "`json:\"obj_array_field\"`" + `
@@ -483,7 +487,7 @@ func TestDynamicStructJSON(t *testing.T) {
 }`,
 			fieldAndNestFields: map[string][]string{
 				"StringField": nil,
-				"ObjField":    {"Id", "Name", "ObjArrayField"},
+				"ObjField":    {"ID", "Name", "ObjArrayField"},
 			},
 		},
 		{
@@ -509,18 +513,18 @@ func TestDynamicStructJSON(t *testing.T) {
 			wantDefinition: `type DynamicStruct struct {
 	ObjField struct {
 		Boss bool
-		Id float64
+		ID int64
 		Name string
 		ObjobjField struct {
 			Status string
-			UserId float64
+			UserID int64
 		}
 	}
 	StringField string
 }`,
 			fieldAndNestFields: map[string][]string{
 				"StringField": nil,
-				"ObjField":    {"Boss", "Id", "Name", "ObjobjField"},
+				"ObjField":    {"Boss", "ID", "Name", "ObjobjField"},
 			},
 		},
 		{
@@ -587,7 +591,7 @@ func TestDynamicStructJSON(t *testing.T) {
 			wantNumF: 2,
 			// FIXME: [][]interface ではなく [][]string になるように修正したい
 			wantDefinition: `type DynamicStruct struct {
-	StringArrayField [][]interface {}
+	StringArrayField [][]string
 	StringField string
 }`,
 			fieldAndNestFields: map[string][]string{
@@ -619,7 +623,7 @@ func TestDynamicStructJSON(t *testing.T) {
 				"StringField":      nil,
 			},
 		},
-		// Note: トップレベルが配列のJSONは、配列の1番目の要素を使って処理する
+		// Note: トップレベルが配列のJSONは、全ての要素をマージして処理する
 		{
 			name: "TopLevelIsArrayNestFalse",
 			data: []byte(`
@@ -660,7 +664,77 @@ func TestDynamicStructJSON(t *testing.T) {
 				"StringField":      nil,
 			},
 		},
-		// Note: トップレベルが配列のJSONは、配列の1番目の要素を使って処理する（nest=trueでも同様）
+		{
+			name: "HasTypedArrays",
+			data: []byte(`
+{
+	"strs":["a","b"],
+	"bools":[true,false],
+	"mixed":[1,"a"],
+	"nested":[[1,2],[],[3.5]],
+	"nested_empty":[[],[]],
+	"empty":[],
+	"nulls":[null,1]
+}
+`),
+			dt:       typeJSON,
+			nest:     true,
+			useTag:   false,
+			wantNumF: 7,
+			wantDefinition: `type DynamicStruct struct {
+	Bools []bool
+	Empty []interface {}
+	Mixed []interface {}
+	Nested [][]float64
+	NestedEmpty [][]interface {}
+	Nulls []interface {}
+	Strs []string
+}`,
+			fieldAndNestFields: map[string][]string{
+				"Empty":  nil,
+				"Nested": nil,
+				"Strs":   nil,
+			},
+		},
+		{
+			name: "HasWidenedNumbers",
+			data: []byte(`
+[
+	{
+		"id":1234567890123456789,
+		"big":1,
+		"float_array":[1,2.5],
+		"int_array":[-1,2],
+		"obj":{"v":1}
+	},
+	{
+		"id":1,
+		"big":18446744073709551615,
+		"float_array":[3],
+		"obj":{"v":0.5}
+	}
+]
+`),
+			dt:       typeJSON,
+			nest:     true,
+			useTag:   false,
+			wantNumF: 5,
+			wantDefinition: `type DynamicStruct struct {
+	Big uint64
+	FloatArray []float64
+	ID int64
+	IntArray []int64
+	Obj struct {
+		V float64
+	}
+}`,
+			fieldAndNestFields: map[string][]string{
+				"Big": nil,
+				"ID":  nil,
+				"Obj": {"V"},
+			},
+		},
+		// Note: トップレベルが配列のJSONは、全ての要素をマージして処理する（nest=falseでも同様）
 		{
 			name: "TopLevelIsArrayNestTrue",
 			data: []byte(`
@@ -691,15 +765,15 @@ func TestDynamicStructJSON(t *testing.T) {
 			useTag:   false,
 			wantNumF: 3,
 			wantDefinition: `type DynamicStruct struct {
-	ObjobjField struct {
+	ObjobjField *struct {
 		Status string
-		UserId float64
+		UserID int64
 	}
 	StringArrayField []string
 	StringField string
 }`,
 			fieldAndNestFields: map[string][]string{
-				"ObjobjField":      {"Status", "UserId"},
+				"ObjobjField":      {"Status", "UserID"},
 				"StringArrayField": nil,
 				"StringField":      nil,
 			},
@@ -774,6 +848,363 @@ func TestDynamicStructJSON(t *testing.T) {
 	}
 }
 
+func TestSetEmptyArrayElemType(t *testing.T) {
+	t.Parallel()
+
+	dec, err := FromYAML([]byte(`
+empty: []
+nums: [1, 2.5]
+`))
+	if err != nil {
+		t.Fatalf("unexpected error is returned from FromYAML: %v", err)
+	}
+
+	ds, err := dec.SetEmptyArrayElemType(reflect.TypeOf("")).DynamicStruct(false, false)
+	if err != nil {
+		t.Fatalf("unexpected error is returned from DynamicStruct: %v", err)
+	}
+
+	want := `type DynamicStruct struct {
+	Empty []string
+	Nums []float64
+}`
+	if d := cmp.Diff(ds.Definition(), want); d != "" {
+		t.Errorf("mismatch Definition: (-got +want)\n%s", d)
+	}
+}
+
+func TestDynamicStructHeterogeneousArray(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(`
+[
+	{"id":1,"name":"a","items":[{"k":"x"}]},
+	{"id":2,"email":"b@example.com","score":null,"items":[{"k":"y","v":1.5}]},
+	{"id":3,"score":10,"items":[]}
+]
+`)
+
+	tests := []struct {
+		name           string
+		sampleN        int
+		wantDefinition string
+	}{
+		{
+			name: "AllElements",
+			wantDefinition: `type DynamicStruct struct {
+	Email *string ` + "`json:\"email,omitempty\"`" + `
+	ID int64 ` + "`json:\"id\"`" + `
+	Items []*struct {
+		K string ` + "`json:\"k\"`" + `
+		V *float64 ` + "`json:\"v,omitempty\"`" + `
+	} ` + "`json:\"items\"`" + `
+	Name *string ` + "`json:\"name,omitempty\"`" + `
+	Score *int64 ` + "`json:\"score,omitempty\"`" + `
+}`,
+		},
+		{
+			name:    "Sample",
+			sampleN: 1,
+			wantDefinition: `type DynamicStruct struct {
+	ID int64 ` + "`json:\"id\"`" + `
+	Items []*struct {
+		K string ` + "`json:\"k\"`" + `
+	} ` + "`json:\"items\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dec, err := FromJSON(data)
+			if err != nil {
+				t.Fatalf("unexpected error is returned from FromJSON: %v", err)
+			}
+
+			ds, err := dec.SetArraySampleSize(tt.sampleN).DynamicStruct(true, true)
+			if err != nil {
+				t.Fatalf("unexpected error is returned from DynamicStruct: %v", err)
+			}
+			if d := cmp.Diff(ds.Definition(), tt.wantDefinition); d != "" {
+				t.Errorf("mismatch Definition: (-got +want)\n%s", d)
+			}
+		})
+	}
+
+	// keys missing in the first element are not lost
+	gs, err := JSONToGetters(data, true)
+	if err != nil {
+		t.Fatalf("unexpected error is returned from JSONToGetters: %v", err)
+	}
+	if email, _ := gs[1].String("Email"); email != "b@example.com" {
+		t.Errorf("unexpected Email: %s", email)
+	}
+	if score, _ := gs[2].Int64("Score"); score != 10 {
+		t.Errorf("unexpected Score: %d", score)
+	}
+	if score, _ := gs[1].Get("Score"); score != nil {
+		t.Errorf("unexpected Score: %v", score)
+	}
+}
+
+func TestDynamicStructEmptyArrayInElement(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		data           []byte
+		wantDefinition string
+	}{
+		{
+			name: "Strings",
+			data: []byte(`[{"t":[]},{"t":["x"]}]`),
+			wantDefinition: `type DynamicStruct struct {
+	T []string ` + "`json:\"t\"`" + `
+}`,
+		},
+		{
+			name: "NestedObject",
+			data: []byte(`[{"o":{"t":["x"]}},{"o":{"t":[]}}]`),
+			wantDefinition: `type DynamicStruct struct {
+	O struct {
+		T []string ` + "`json:\"t\"`" + `
+	} ` + "`json:\"o\"`" + `
+}`,
+		},
+		{
+			name: "NestedArray",
+			data: []byte(`[{"t":[]},{"t":[["x"]]}]`),
+			wantDefinition: `type DynamicStruct struct {
+	T [][]string ` + "`json:\"t\"`" + `
+}`,
+		},
+		{
+			name: "Objects",
+			data: []byte(`[{"t":[]},{"t":[{"k":1}]}]`),
+			wantDefinition: `type DynamicStruct struct {
+	T []*struct {
+		K int64 ` + "`json:\"k\"`" + `
+	} ` + "`json:\"t\"`" + `
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dec, err := FromJSON(tt.data)
+			if err != nil {
+				t.Fatalf("unexpected error is returned from FromJSON: %v", err)
+			}
+
+			ds, err := dec.DynamicStruct(true, true)
+			if err != nil {
+				t.Fatalf("unexpected error is returned from DynamicStruct: %v", err)
+			}
+			if d := cmp.Diff(ds.Definition(), tt.wantDefinition); d != "" {
+				t.Errorf("mismatch Definition: (-got +want)\n%s", d)
+			}
+
+			if _, err := dec.DecodeToSlice(ds); err != nil {
+				t.Errorf("unexpected error is returned from DecodeToSlice: %v", err)
+			}
+		})
+	}
+}
+
+func TestDynamicStructConflictedKeysInArray(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		data           []byte
+		wantDefinition string
+	}{
+		{
+			name: "TopLevel",
+			data: []byte(`[{"fooBar":1},{"foo_bar":2}]`),
+			wantDefinition: `type DynamicStruct struct {
+	FooBar *int64 ` + "`json:\"fooBar,omitempty\"`" + `
+	FooBar2 *int64 ` + "`json:\"foo_bar,omitempty\"`" + `
+}`,
+		},
+		{
+			name: "Nested",
+			data: []byte(`[{"o":{"foo_bar":1}},{"o":{"foo_bar":3,"fooBar":2}}]`),
+			wantDefinition: `type DynamicStruct struct {
+	O struct {
+		FooBar *int64 ` + "`json:\"fooBar,omitempty\"`" + `
+		FooBar2 int64 ` + "`json:\"foo_bar\"`" + `
+	} ` + "`json:\"o\"`" + `
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dec, err := FromJSON(tt.data)
+			if err != nil {
+				t.Fatalf("unexpected error is returned from FromJSON: %v", err)
+			}
+
+			ds, err := dec.DynamicStruct(true, true)
+			if err != nil {
+				t.Fatalf("unexpected error is returned from DynamicStruct: %v", err)
+			}
+			if d := cmp.Diff(ds.Definition(), tt.wantDefinition); d != "" {
+				t.Errorf("mismatch Definition: (-got +want)\n%s", d)
+			}
+
+			// the values of all keys are decoded
+			sp, err := dec.DecodeToSlice(ds)
+			if err != nil {
+				t.Fatalf("unexpected error is returned from DecodeToSlice: %v", err)
+			}
+			got, err := json.Marshal(sp)
+			if err != nil {
+				t.Fatalf("unexpected error is returned from json.Marshal: %v", err)
+			}
+			if d := cmp.Diff(string(got), string(tt.data)); d != "" {
+				t.Errorf("mismatch: (-got +want)\n%s", d)
+			}
+		})
+	}
+}
+
+func TestDecodeToSlice(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(`[{"id":1,"name":"a","obj":{"k":[1,2]}},{"id":2,"NAME":"b","obj":null}]`)
+	dec, err := FromJSON(data)
+	if err != nil {
+		t.Fatalf("unexpected error is returned from FromJSON: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		ds   *dynamicstruct.DynamicStruct
+		want string
+	}{
+		{
+			name: "Assign",
+			ds: mustBuild(t, dynamicstruct.NewBuilder().
+				AddTypeWithTag("ID", reflect.TypeOf(int64(0)), `json:"id"`).
+				AddStringWithTag("Name", `json:"name"`).
+				AddMapWithTag("Obj", "", nil, `json:"obj"`)),
+			want: `[{"id":1,"name":"a","obj":{"k":[1,2]}},{"id":2,"name":"b","obj":null}]`,
+		},
+		{
+			// json.RawMessage can not be assigned from maps, so the round trip is used
+			name: "Fallback",
+			ds: mustBuild(t, dynamicstruct.NewBuilder().
+				AddTypeWithTag("ID", reflect.TypeOf(int64(0)), `json:"id"`).
+				AddTypeWithTag("Obj", reflect.TypeOf(json.RawMessage{}), `json:"obj"`)),
+			want: `[{"id":1,"obj":{"k":[1,2]}},{"id":2,"obj":null}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			sp, err := dec.DecodeToSlice(tt.ds)
+			if err != nil {
+				t.Fatalf("unexpected error is returned from DecodeToSlice: %v", err)
+			}
+
+			got, err := json.Marshal(sp)
+			if err != nil {
+				t.Fatalf("unexpected error is returned from json.Marshal: %v", err)
+			}
+			if d := cmp.Diff(string(got), tt.want); d != "" {
+				t.Errorf("mismatch: (-got +want)\n%s", d)
+			}
+		})
+	}
+}
+
+func TestDecodeToSliceYAMLCaseSensitive(t *testing.T) {
+	t.Parallel()
+
+	data := []byte("- name: a\n- NAME: b\n")
+	dec, err := FromYAML(data)
+	if err != nil {
+		t.Fatalf("unexpected error is returned from FromYAML: %v", err)
+	}
+	ds := mustBuild(t, dynamicstruct.NewBuilder().AddStringWithTag("Name", `yaml:"name"`))
+
+	sp, err := dec.DecodeToSlice(ds)
+	if err != nil {
+		t.Fatalf("unexpected error is returned from DecodeToSlice: %v", err)
+	}
+
+	// keys of YAML are matched case sensitively like yaml.Unmarshal
+	want := ds.NewSliceOfPtr(0, 0)
+	if err := yaml.Unmarshal(data, want); err != nil {
+		t.Fatalf("unexpected error is returned from yaml.Unmarshal: %v", err)
+	}
+	if d := cmp.Diff(sp, want); d != "" {
+		t.Errorf("mismatch: (-got +want)\n%s", d)
+	}
+
+	_, unknown, err := DecodeInto(ds, FormatYAML, []byte("NAME: b\n"))
+	if err != nil {
+		t.Fatalf("unexpected error is returned from DecodeInto: %v", err)
+	}
+	if d := cmp.Diff(unknown, []string{"NAME"}); d != "" {
+		t.Errorf("mismatch unknown keys: (-got +want)\n%s", d)
+	}
+}
+
+func mustBuild(t *testing.T, b *dynamicstruct.Builder) *dynamicstruct.DynamicStruct {
+	t.Helper()
+
+	ds, err := b.Build()
+	if err != nil {
+		t.Fatalf("unexpected error is returned from Build: %v", err)
+	}
+	return ds
+}
+
+func TestJSONToGetterNumbers(t *testing.T) {
+	t.Parallel()
+
+	g, err := JSONToGetter([]byte(`{"id":1234567890123456789,"score":1e3,"neg":-9223372036854775808}`), true)
+	if err != nil {
+		t.Fatalf("unexpected error is returned from JSONToGetter: %v", err)
+	}
+
+	// 64-bit IDs are not rounded to float64
+	if id, ok := g.Int64("ID"); !ok || id != 1234567890123456789 {
+		t.Errorf("unexpected ID: %d, %v", id, ok)
+	}
+	if neg, ok := g.Int64("Neg"); !ok || neg != -9223372036854775808 {
+		t.Errorf("unexpected Neg: %d, %v", neg, ok)
+	}
+	if score, ok := g.Float64("Score"); !ok || score != 1000 {
+		t.Errorf("unexpected Score: %f, %v", score, ok)
+	}
+
+	if _, err := FromJSON([]byte(`{"id":1} {"id":2}`)); err == nil {
+		t.Errorf("error is expected for multiple values")
+	} else if want := "invalid data after top-level value: unexpected token {"; err.Error() != want {
+		t.Errorf("unexpected error. got: %v, want: %s", err, want)
+	}
+	if _, err := FromJSON([]byte(`{"id":1} ]`)); err == nil {
+		t.Errorf("error is expected for invalid trailing data")
+	}
+}
+
 func TestDynamicStructYAML(t *testing.T) {
 	t.Parallel()
 
@@ -862,13 +1293,13 @@ obj_field:
 			wantNumF: 2,
 			wantDefinition: `type DynamicStruct struct {
 	ObjField struct {
-		Id int
+		ID int
 		Name string
 	}
 	StringField string
 }`,
 			fieldAndNestFields: map[string][]string{
-				"ObjField":    {"Id", "Name"},
+				"ObjField":    {"ID", "Name"},
 				"StringField": nil,
 			},
 		},
@@ -886,13 +1317,13 @@ obj_field:
 			wantNumF: 2,
 			wantDefinition: `type DynamicStruct struct {
 	ObjField struct {
-		Id int ` + "`yaml:\"id\"`" + `
+		ID int ` + "`yaml:\"id\"`" + `
 		Name string ` + "`yaml:\"name\"`" + `
 	} ` + "`yaml:\"obj_field\"`" + `
 	StringField string ` + "`yaml:\"string_field\"`" + `
 }`,
 			fieldAndNestFields: map[string][]string{
-				"ObjField":    {"Id", "Name"},
+				"ObjField":    {"ID", "Name"},
 				"StringField": nil,
 			},
 		},
@@ -915,17 +1346,17 @@ obj_field:
 			wantDefinition: `type DynamicStruct struct {
 	ObjField struct {
 		Boss bool
-		Id int
+		ID int
 		Name string
 		ObjobjField struct {
 			Status string
-			UserId int
+			UserID int
 		}
 	}
 	StringField string
 }`,
 			fieldAndNestFields: map[string][]string{
-				"ObjField":    {"Boss", "Id", "Name", "ObjobjField"},
+				"ObjField":    {"Boss", "ID", "Name", "ObjobjField"},
 				"StringField": nil,
 			},
 		},
@@ -968,19 +1399,19 @@ arr_obj_field:
 			useTag:   false,
 			wantNumF: 3,
 			wantDefinition: `type DynamicStruct struct {
-	ArrObjField []struct {
+	ArrObjField []*struct {
 		Aid int
 		Aname string
 	}
 	ObjField struct {
 		Status string
-		UserId int
+		UserID int
 	}
 	StringField string
 }`,
 			fieldAndNestFields: map[string][]string{
 				"ArrObjField": nil,
-				"ObjField":    {"Status", "UserId"},
+				"ObjField":    {"Status", "UserID"},
 				"StringField": nil,
 			},
 		},
@@ -1009,7 +1440,7 @@ string_array_field:
 			wantNumF: 2,
 			// FIXME: [][]interface ではなく [][]string になるように修正したい
 			wantDefinition: `type DynamicStruct struct {
-	StringArrayField [][]interface {}
+	StringArrayField [][]string
 	StringField string
 }`,
 			fieldAndNestFields: map[string][]string{
@@ -1078,10 +1509,216 @@ string_array_field:
 	}
 }
 
-func TestDynamicStructFixmeXml(t *testing.T) {
+func TestDynamicStructTOML(t *testing.T) {
 	t.Parallel()
 
 	tests := []decoderTest{
+		{
+			name:     "SingleTOML",
+			data:     singleTOML,
+			dt:       typeTOML,
+			nest:     true,
+			useTag:   true,
+			wantNumF: 7,
+			wantDefinition: `type DynamicStruct struct {
+	ArrayStringField []string ` + "`toml:\"array_string_field\"`" + `
+	ArrayStructField []*struct {
+		Kkk string ` + "`toml:\"kkk\"`" + `
+		Vvvv string ` + "`toml:\"vvvv\"`" + `
+	} ` + "`toml:\"array_struct_field\"`" + `
+	BoolField bool ` + "`toml:\"bool_field\"`" + `
+	Float32Field string ` + "`toml:\"float32_field\"`" + `
+	IntField int64 ` + "`toml:\"int_field\"`" + `
+	StringField string ` + "`toml:\"string_field\"`" + `
+	StructPtrField struct {
+		Key string ` + "`toml:\"key\"`" + `
+		Value string ` + "`toml:\"value\"`" + `
+	} ` + "`toml:\"struct_ptr_field\"`" + `
+}`,
+			fieldAndNestFields: map[string][]string{
+				"ArrayStringField": nil,
+				"ArrayStructField": nil,
+				"BoolField":        nil,
+				"Float32Field":     nil,
+				"IntField":         nil,
+				"StringField":      nil,
+				"StructPtrField":   {"Key", "Value"},
+			},
+		},
+		{
+			name:     "SingleTOMLNonNest",
+			data:     singleTOML,
+			dt:       typeTOML,
+			nest:     false,
+			useTag:   false,
+			wantNumF: 7,
+			wantDefinition: `type DynamicStruct struct {
+	ArrayStringField []string
+	ArrayStructField []map[string]interface {}
+	BoolField bool
+	Float32Field string
+	IntField int64
+	StringField string
+	StructPtrField map[string]interface {}
+}`,
+			fieldAndNestFields: map[string][]string{
+				"ArrayStructField": nil,
+				"StructPtrField":   nil,
+			},
+		},
+		{
+			name: "HasDatetimeAndInlineTable",
+			data: []byte(`
+id = 1
+created_at = 1979-05-27T07:32:00Z
+point = { x = 1, y = 2.5 }
+`),
+			dt:       typeTOML,
+			nest:     true,
+			useTag:   false,
+			wantNumF: 3,
+			wantDefinition: `type DynamicStruct struct {
+	CreatedAt time.Time
+	ID int64
+	Point struct {
+		X int64
+		Y float64
+	}
+}`,
+			fieldAndNestFields: map[string][]string{
+				"CreatedAt": nil,
+				"ID":        nil,
+				"Point":     {"X", "Y"},
+			},
+		},
+		{
+			name:         "Invalid",
+			data:         []byte(`string_field = `),
+			dt:           typeTOML,
+			nest:         false,
+			useTag:       false,
+			wantErrorNew: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // See: https://gist.github.com/posener/92a55c4cd441fc5e5e85f27bca008721
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dec, err := FromTOML(tt.data)
+			if err != nil {
+				if !tt.wantErrorNew {
+					t.Fatalf("unexpected error is returned from FromTOML: %v", err)
+				}
+				return
+			} else if tt.wantErrorNew {
+				t.Fatalf("error is expected but it does not occur from FromTOML. data: %q", string(tt.data))
+			}
+
+			testCorrectCase(t, tt, dec)
+		})
+	}
+}
+
+func TestTOMLToGetterValues(t *testing.T) {
+	t.Parallel()
+
+	g, err := TOMLToGetter(singleTOML, true)
+	if err != nil {
+		t.Fatalf("unexpected error is returned from TOMLToGetter: %v", err)
+	}
+
+	if got, _ := g.Int64("IntField"); got != 45678 {
+		t.Errorf("unexpected IntField: %d", got)
+	}
+
+	gs, err := g.MapGet("ArrayStructField", func(i int, g *structil.Getter) (interface{}, error) {
+		s, _ := g.String("Kkk")
+		return s, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error is returned from MapGet: %v", err)
+	}
+	if d := cmp.Diff(gs, []interface{}{"kkk1", "kkk2", "kkk3"}); d != "" {
+		t.Errorf("mismatch ArrayStructField: (-got +want)\n%s", d)
+	}
+}
+
+func TestDynamicStructXML(t *testing.T) {
+	t.Parallel()
+
+	tests := []decoderTest{
+		{
+			name:     "SingleXML",
+			data:     singleXML,
+			dt:       typeXML,
+			nest:     true,
+			useTag:   true,
+			wantNumF: 8,
+			wantDefinition: `type DynamicStruct struct {
+	ArrayStringField []string ` + "`xml:\"array_string_field\"`" + `
+	ArrayStructField []*struct {
+		Kkk string ` + "`xml:\"kkk\"`" + `
+		Vvvv string ` + "`xml:\"vvvv\"`" + `
+	} ` + "`xml:\"array_struct_field\"`" + `
+	BoolField string ` + "`xml:\"bool_field\"`" + `
+	Float32Field string ` + "`xml:\"float32_field\"`" + `
+	IntField string ` + "`xml:\"int_field\"`" + `
+	NullField string ` + "`xml:\"null_field\"`" + `
+	StringField string ` + "`xml:\"string_field\"`" + `
+	StructPtrField struct {
+		Key string ` + "`xml:\"key\"`" + `
+		Value string ` + "`xml:\"value\"`" + `
+	} ` + "`xml:\"struct_ptr_field\"`" + `
+}`,
+			fieldAndNestFields: map[string][]string{
+				"ArrayStringField": nil,
+				"ArrayStructField": nil,
+				"StringField":      nil,
+				"StructPtrField":   {"Key", "Value"},
+			},
+		},
+		{
+			name: "HasAttrAndChardataWithNamespace",
+			data: []byte(`
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <Price currency="USD">34.5</Price>
+    <Item id="1" name="a"><name>x</name></Item>
+    <Item id="2" name="b"><name>y</name></Item>
+  </soap:Body>
+</soap:Envelope>
+`),
+			dt:       typeXML,
+			nest:     false,
+			useTag:   false,
+			wantNumF: 1,
+			wantDefinition: `type DynamicStruct struct {
+	Body struct {
+		Item []*struct {
+			ID string ` + "`xml:\"id,attr\"`" + `
+			Name string ` + "`xml:\"name\"`" + `
+			NameAttr string ` + "`xml:\"name,attr\"`" + `
+		} ` + "`xml:\"Item\"`" + `
+		Price struct {
+			Currency string ` + "`xml:\"currency,attr\"`" + `
+			Text string ` + "`xml:\",chardata\"`" + `
+		} ` + "`xml:\"Price\"`" + `
+	} ` + "`xml:\"Body\"`" + `
+}`,
+			fieldAndNestFields: map[string][]string{
+				"Body": {"Item", "Price"},
+			},
+		},
+		{
+			name:         "Invalid",
+			data:         []byte(`<root><a></root>`),
+			dt:           typeXML,
+			nest:         false,
+			useTag:       false,
+			wantErrorNew: true,
+		},
 		{
 			name: "ValidYamlButTypeIsInvalid",
 			data: []byte(`
@@ -1121,7 +1758,7 @@ bool_field: false
 		t.Run(tt.name, func(t *testing.T) {
 			t.Parallel()
 
-			_, err := FromXML(tt.data)
+			dec, err := FromXML(tt.data)
 			if err != nil {
 				if !tt.wantErrorNew {
 					t.Fatalf("unexpected error is returned from FromXML: %v", err)
@@ -1130,10 +1767,72 @@ bool_field: false
 			} else if tt.wantErrorNew {
 				t.Fatalf("error is expected but it does not occur from FromXML	. data: %q", string(tt.data))
 			}
+
+			testCorrectCase(t, tt, dec)
 		})
 	}
 }
 
+func TestXMLToGetterValues(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(`<feed><entry id="1">a</entry><entry id="2">b</entry><entry>c</entry><title lang="en">Partner</title></feed>`)
+	g, err := XMLToGetter(data)
+	if err != nil {
+		t.Fatalf("unexpected error is returned from XMLToGetter: %v", err)
+	}
+
+	got, err := g.MapGet("Entry", func(i int, g *structil.Getter) (interface{}, error) {
+		id, _ := g.String("ID")
+		text, _ := g.String("Text")
+		return id + ":" + text, nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error is returned from MapGet: %v", err)
+	}
+	if d := cmp.Diff(got, []interface{}{"1:a", "2:b", ":c"}); d != "" {
+		t.Errorf("mismatch Entry: (-got +want)\n%s", d)
+	}
+
+	tg, ok := g.GetGetter("Title")
+	if !ok {
+		t.Fatalf("GetGetter(Title) should be ok")
+	}
+	if lang, _ := tg.String("Lang"); lang != "en" {
+		t.Errorf("unexpected Title.Lang: %s", lang)
+	}
+}
+
+func TestDynamicStructXMLOptionalChardata(t *testing.T) {
+	t.Parallel()
+
+	// Text exists in only the first item, so it is an optional field
+	data := []byte(`<feed><item id="1">hello</item><item id="2"><n>5</n></item></feed>`)
+	dec, err := FromXML(data)
+	if err != nil {
+		t.Fatalf("unexpected error is returned from FromXML: %v", err)
+	}
+	ds, err := dec.DynamicStruct(true, true)
+	if err != nil {
+		t.Fatalf("unexpected error is returned from DynamicStruct: %v", err)
+	}
+
+	want := `type DynamicStruct struct {
+	Item []*struct {
+		ID string ` + "`xml:\"id,attr\"`" + `
+		N *string ` + "`xml:\"n,omitempty\"`" + `
+		Text *string ` + "`xml:\",chardata\"`" + `
+	} ` + "`xml:\"item\"`" + `
+}`
+	if d := cmp.Diff(ds.Definition(), want); d != "" {
+		t.Errorf("mismatch Definition: (-got +want)\n%s", d)
+	}
+
+	if err := xml.Unmarshal(data, ds.NewInterface()); err != nil {
+		t.Errorf("unexpected error is returned from xml.Unmarshal: %v", err)
+	}
+}
+
 func testCorrectCase(t *testing.T, tt decoderTest, dec *Decoder) {
 	t.Helper()
 
@@ -1177,6 +1876,16 @@ func testCorrectCase(t *testing.T, tt decoderTest, dec *Decoder) {
 			if err != nil {
 				t.Fatalf("unexpected error is returned from YAMLToGetter: %v", err)
 			}
+		case typeTOML:
+			g, err = TOMLToGetter(tt.data, tt.nest)
+			if err != nil {
+				t.Fatalf("unexpected error is returned from TOMLToGetter: %v", err)
+			}
+		case typeXML:
+			g, err = XMLToGetter(tt.data)
+			if err != nil {
+				t.Fatalf("unexpected error is returned from XMLToGetter: %v", err)
+			}
 		}
 
 		for n, nests := range tt.fieldAndNestFields {
@@ -1203,3 +1912,56 @@ func testToGetter(t *testing.T, n string, nests []string, g *structil.Getter) {
 		}
 	}
 }
+
+func TestToGetters(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		data        []byte
+		dt          int
+		nest        bool
+		wantStrings []string
+		wantError   bool
+	}{
+		{name: "ArrayJSON", data: arrayJSON, dt: typeJSON, nest: true, wantStrings: []string{"かきくけこ", "さしすせそ"}},
+		{name: "ArrayJSONNonNest", data: arrayJSON, dt: typeJSON, nest: false, wantStrings: []string{"かきくけこ", "さしすせそ"}},
+		{name: "SingleJSON", data: singleJSON, dt: typeJSON, nest: true, wantStrings: []string{"かきくけこ"}},
+		{name: "EmptyArrayJSON", data: []byte(`[]`), dt: typeJSON, nest: true, wantStrings: []string{}},
+		{name: "InvalidJSON", data: []byte(`{`), dt: typeJSON, nest: true, wantError: true},
+		{name: "ArrayYAML", data: arrayYAML, dt: typeYAML, nest: true, wantStrings: []string{"かきくけこ", "さしすせそ"}},
+		{name: "SingleYAML", data: singleYAML, dt: typeYAML, nest: false, wantStrings: []string{"かきくけこ"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt // See: https://gist.github.com/posener/92a55c4cd441fc5e5e85f27bca008721
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var gs []*structil.Getter
+			var err error
+			switch tt.dt {
+			case typeJSON:
+				gs, err = JSONToGetters(tt.data, tt.nest)
+			case typeYAML:
+				gs, err = YAMLToGetters(tt.data, tt.nest)
+			}
+			if err != nil {
+				if !tt.wantError {
+					t.Fatalf("unexpected error is returned: %v", err)
+				}
+				return
+			} else if tt.wantError {
+				t.Fatalf("error is expected but it does not occur. data: %q", string(tt.data))
+			}
+
+			got := make([]string, len(gs))
+			for i, g := range gs {
+				got[i], _ = g.String("StringField")
+			}
+			if d := cmp.Diff(got, tt.wantStrings); d != "" {
+				t.Fatalf("mismatch StringField values: (-got +want)\n%s", d)
+			}
+		})
+	}
+}
@@ -57,13 +57,13 @@ func ExampleDecoder_DynamicStruct_json() {
 	// Output:
 	//type DynamicStruct struct {
 	//	ArrayStringField []string `json:"array_string_field"`
-	//	ArrayStructField []struct {
+	//	ArrayStructField []*struct {
 	//		Kkk string `json:"kkk"`
 	//		Vvvv string `json:"vvvv"`
 	//	} `json:"array_struct_field"`
 	//	BoolField bool `json:"bool_field"`
 	//	Float32Field float64 `json:"float32_field"`
-	//	IntField float64 `json:"int_field"`
+	//	IntField int64 `json:"int_field"`
 	//	NullField interface {} `json:"null_field"`
 	//	StringField string `json:"string_field"`
 	//	StructPtrField struct {
@@ -113,7 +113,7 @@ func ExampleJSONToGetter() {
 	}
 
 	s, _ := g.String("StringField")   // field names of DynamicStruct are camelized original json field key
-	i, _ := g.Float64("IntField")     // Note: type of unmarshalled number fields are float64. See: https://golang.org/pkg/encoding/json/#Unmarshal
+	i, _ := g.Int64("IntField")       // Note: type of integer fields are int64, and the other number fields are float64
 	f, _ := g.Float64("Float32Field") // same as above
 	b, _ := g.Bool("BoolField")
 	arrS, _ := g.Get("ArrayStringField")
@@ -125,22 +125,22 @@ func ExampleJSONToGetter() {
 	arrStrct, _ := g.Slice("ArrayStructField")
 	gArrZero, _ := structil.NewGetter(arrStrct[0])
 	sGArrZero, _ := gArrZero.String("Kkk")
-	fGArrZero, _ := gArrZero.Float64("Vvvv")
+	iGArrZero, _ := gArrZero.Int64("Vvvv")
 
 	fmt.Printf("g.IsStruct(StructPtrField) = %v\n", g.IsStruct("StructPtrField"))
 	fmt.Printf("g.IsSlice(ArrayStructField) = %v\n", g.IsSlice("ArrayStructField"))
 	fmt.Printf(
-		"num of fields=%d\n'StringField'=%s\n'IntField'=%f\n'Float32Field'=%f\n'BoolField'=%t\n'ArrayStringField'=%+v\n'NullField'=%+v\n'StructPtrField.Key'=%s\n'ArrayStructField[0].Kkk'=%s\n'ArrayStructField[0].Vvvv'=%f\n",
+		"num of fields=%d\n'StringField'=%s\n'IntField'=%d\n'Float32Field'=%f\n'BoolField'=%t\n'ArrayStringField'=%+v\n'NullField'=%+v\n'StructPtrField.Key'=%s\n'ArrayStructField[0].Kkk'=%s\n'ArrayStructField[0].Vvvv'=%d\n",
 		g.NumField(),
 		s,
-		i, // Note: type of unmarshalled number fields are float64. See: https://golang.org/pkg/encoding/json/#Unmarshal
-		f, // same as above
+		i,
+		f,
 		b,
 		arrS,
 		null,
 		ggKey,
 		sGArrZero,
-		fGArrZero,
+		iGArrZero,
 	)
 
 	m := g.ToMap()
@@ -151,14 +151,14 @@ func ExampleJSONToGetter() {
 	// g.IsSlice(ArrayStructField) = true
 	// num of fields=8
 	// 'StringField'=かきくけこ
-	// 'IntField'=45678.000000
+	// 'IntField'=45678
 	// 'Float32Field'=9.876000
 	// 'BoolField'=false
 	// 'ArrayStringField'=[array_str_1 array_str_2]
 	// 'NullField'=<nil>
 	// 'StructPtrField.Key'=hugakey
 	// 'ArrayStructField[0].Kkk'=kkk1
-	// 'ArrayStructField[0].Vvvv'=12.000000
+	// 'ArrayStructField[0].Vvvv'=12
 	// g.ToMap()[StringField] =かきくけこ
 }
 
@@ -196,17 +196,17 @@ arr_obj_field:
 
 	// Output:
 	//type DynamicStruct struct {
-	//	ArrObjField []struct {
+	//	ArrObjField []*struct {
 	//		Aid int `yaml:"aid"`
 	//		Aname string `yaml:"aname"`
 	//	} `yaml:"arr_obj_field"`
 	//	ObjField struct {
 	//		Boss bool `yaml:"boss"`
-	//		Id int `yaml:"id"`
+	//		ID int `yaml:"id"`
 	//		Name string `yaml:"name"`
 	//		ObjobjField struct {
 	// 			Status string `yaml:"status"`
-	// 			UserId int `yaml:"user_id"`
+	// 			UserID int `yaml:"user_id"`
 	//		} `yaml:"objobj_field"`
 	//	} `yaml:"obj_field"`
 	//	StringField string `yaml:"string_field"`
@@ -240,7 +240,7 @@ arr_obj_field:
 	gg, _ := g.GetGetter("ObjField")
 	ggName, _ := gg.String("Name")
 	ggg, _ := gg.GetGetter("ObjobjField")
-	gggUserID, _ := ggg.Int("UserId")
+	gggUserID, _ := ggg.Int("UserID")
 
 	ao, _ := g.Slice("ArrObjField")
 	gAoZero, err := structil.NewGetter(ao[0])
@@ -250,7 +250,7 @@ arr_obj_field:
 	fmt.Printf("g.IsStruct(ObjField) = %v\n", g.IsStruct("ObjField"))
 	fmt.Printf("g.IsSlice(ArrObjField) = %v\n", g.IsSlice("ArrObjField"))
 	fmt.Printf(
-		"num of fields=%d\n'StringField'=%s\n'ObjField.Name'=%s\n'ObjobjField.UserId'=%d\n'ArrObjField[0].Aid'=%d\n'ArrObjField[0].Aname'=%s\n",
+		"num of fields=%d\n'StringField'=%s\n'ObjField.Name'=%s\n'ObjobjField.UserID'=%d\n'ArrObjField[0].Aid'=%d\n'ArrObjField[0].Aname'=%s\n",
 		g.NumField(),
 		s,
 		ggName,
@@ -268,7 +268,7 @@ arr_obj_field:
 	// num of fields=3
 	// 'StringField'=あいうえ
 	// 'ObjField.Name'=Test Jiou
-	// 'ObjobjField.UserId'=678
+	// 'ObjobjField.UserID'=678
 	// 'ArrObjField[0].Aid'=45
 	// 'ArrObjField[0].Aname'=Test Mike
 	// g.ToMap()[StringField] =あいうえ
@@ -0,0 +1,130 @@
+package decoder
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/goldeneggg/structil"
+	"github.com/goldeneggg/structil/dynamicstruct"
+)
+
+// Infer returns a DynamicStruct inferred from all samples of format.
+// The DynamicStructs of the samples are merged: the fields of the keys that are missing in some samples
+// are added as optional fields and the conflicted types are widened (see dynamicstruct.Merge).
+// If a sample is a top-level array, the DynamicStruct is inferred from its elements.
+// The kinds of numbers are inferred across all samples like the elements of a single top-level array
+// (e.g. int64 and uint64 over math.MaxInt64 to uint64).
+//
+// The keys are converted to the field names by GoFieldName across all samples (see Decoder.SetFieldNameFunc).
+//
+// The returned DynamicStruct is used as a fixed schema with DecodeInto and DecodeToGetter.
+func Infer(format Format, nest bool, samples ...[]byte) (*dynamicstruct.DynamicStruct, error) {
+	return InferWithFieldNameFunc(format, nest, GoFieldName, samples...)
+}
+
+// InferWithFieldNameFunc is same as Infer, and the keys are converted to the field names by fieldName.
+func InferWithFieldNameFunc(format Format, nest bool, fieldName FieldNameFunc, samples ...[]byte) (*dynamicstruct.DynamicStruct, error) {
+	if len(samples) == 0 {
+		return nil, errors.New("no samples to infer")
+	}
+
+	dt, err := format.dataType()
+	if err != nil {
+		return nil, err
+	}
+
+	decs := make([]*Decoder, len(samples))
+	for i, sample := range samples {
+		if decs[i], err = newDecoder(sample, dt); err != nil {
+			return nil, fmt.Errorf("fail to decode sample %d: %w", i, err)
+		}
+		decs[i].fieldName = fieldName
+	}
+	shareInference(decs)
+
+	var ds *dynamicstruct.DynamicStruct
+	for i, d := range decs {
+		sds, err := d.DynamicStruct(nest, true)
+		if err != nil {
+			return nil, fmt.Errorf("fail to infer sample %d: %w", i, err)
+		}
+
+		if ds == nil {
+			ds = sds
+			continue
+		}
+		ds, err = d.merge(ds, sds)
+		if err != nil {
+			return nil, fmt.Errorf("fail to merge sample %d: %w", i, err)
+		}
+	}
+
+	return ds, nil
+}
+
+// DecodeInto decodes data of format into ds without inferring a DynamicStruct from data.
+// The returned value is a pointer to a new struct of ds, or a pointer to a new slice of ds (e.g. *[]*DynamicStruct)
+// if data is a top-level array.
+//
+// The keys in data that are unknown to ds are ignored and returned as sorted dot separated paths
+// (e.g. "user.nickname"). The keys in array elements are returned once without indexes.
+func DecodeInto(ds *dynamicstruct.DynamicStruct, format Format, data []byte) (interface{}, []string, error) {
+	dt, err := format.dataType()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	unm, err := dt.unmarshal(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var iptr interface{}
+	switch unm.(type) {
+	case map[string]interface{}:
+		iptr = reflect.New(ds.Type()).Interface()
+	case []interface{}:
+		iptr = ds.NewSliceOfPtr(0, 0)
+	default:
+		return nil, nil, fmt.Errorf("unexpected type of data [%v]", unm)
+	}
+
+	d := &Decoder{dt: dt, orgData: data, orgIntf: unm}
+	if err := d.decodeTo(unm, iptr); err != nil {
+		return nil, nil, err
+	}
+
+	a := &assigner{dt: dt}
+	keys := make(map[string]struct{})
+	a.unknownKeys(reflect.TypeOf(iptr).Elem(), unm, "", keys)
+
+	var unknown []string
+	for k := range keys {
+		unknown = append(unknown, k)
+	}
+	sort.Strings(unknown)
+
+	return iptr, unknown, nil
+}
+
+// DecodeToGetter decodes data of format into ds and returns a structil.Getter of it.
+// data must not be a top-level array. See DecodeInto for the returned unknown keys.
+func DecodeToGetter(ds *dynamicstruct.DynamicStruct, format Format, data []byte) (*structil.Getter, []string, error) {
+	v, unknown, err := DecodeInto(ds, format, data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if reflect.TypeOf(v).Elem().Kind() == reflect.Slice {
+		return nil, nil, errors.New("top-level array can not be decoded to a Getter. use DecodeInto instead")
+	}
+
+	g, err := structil.NewGetter(v)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return g, unknown, nil
+}
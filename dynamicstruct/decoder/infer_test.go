@@ -0,0 +1,259 @@
+package decoder_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	. "github.com/goldeneggg/structil/dynamicstruct/decoder"
+)
+
+func TestInfer(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		format         Format
+		samples        [][]byte
+		wantDefinition string
+		wantError      bool
+	}{
+		{
+			name:   "JSON",
+			format: FormatJSON,
+			samples: [][]byte{
+				[]byte(`{"id":1,"user":{"name":"a"}}`),
+				[]byte(`{"id":2.5,"user":{"name":"b","age":20},"tags":["x"]}`),
+			},
+			wantDefinition: `type DynamicStruct struct {
+	ID float64 ` + "`json:\"id\"`" + `
+	Tags []string ` + "`json:\"tags,omitempty\"`" + `
+	User struct {
+		Age *int64 ` + "`json:\"age,omitempty\"`" + `
+		Name string ` + "`json:\"name\"`" + `
+	} ` + "`json:\"user\"`" + `
+}`,
+		},
+		{
+			// non-negative int64 and uint64 over math.MaxInt64 are widened to uint64 like a single top-level array
+			name:   "JSONWidenedUnsignedIntegers",
+			format: FormatJSON,
+			samples: [][]byte{
+				[]byte(`{"id":5,"scores":[]}`),
+				[]byte(`[{"id":18446744073709551615,"scores":[1]},{"scores":[18446744073709551615]}]`),
+			},
+			wantDefinition: `type DynamicStruct struct {
+	ID *uint64 ` + "`json:\"id\"`" + `
+	Scores []uint64 ` + "`json:\"scores\"`" + `
+}`,
+		},
+		{
+			// negative int64 and uint64 over math.MaxInt64 are widened to float64
+			name:   "JSONWidenedIntegers",
+			format: FormatJSON,
+			samples: [][]byte{
+				[]byte(`{"id":-5}`),
+				[]byte(`{"id":18446744073709551615}`),
+			},
+			wantDefinition: `type DynamicStruct struct {
+	ID float64 ` + "`json:\"id\"`" + `
+}`,
+		},
+		{
+			// keys converted to the same name in different samples are not merged into one field,
+			// and the names are assigned in sorted order of the keys of all samples
+			name:   "JSONConflictedKeys",
+			format: FormatJSON,
+			samples: [][]byte{
+				[]byte(`{"foo_bar":1}`),
+				[]byte(`[{"fooBar":"a"},{"foo_bar":2,"fooBar":"b"}]`),
+			},
+			wantDefinition: `type DynamicStruct struct {
+	FooBar *string ` + "`json:\"fooBar,omitempty\"`" + `
+	FooBar2 *int64 ` + "`json:\"foo_bar\"`" + `
+}`,
+		},
+		{
+			name:   "YAMLWithArray",
+			format: FormatYAML,
+			samples: [][]byte{
+				[]byte("- name: a\n- name: b\n  count: 1\n"),
+				[]byte("name: c\n"),
+			},
+			wantDefinition: `type DynamicStruct struct {
+	Count *int ` + "`yaml:\"count,omitempty\"`" + `
+	Name string ` + "`yaml:\"name\"`" + `
+}`,
+		},
+		{
+			name:      "NoSamples",
+			format:    FormatJSON,
+			wantError: true,
+		},
+		{
+			name:      "Invalid",
+			format:    FormatJSON,
+			samples:   [][]byte{[]byte(`{"id":1}`), []byte(`{`)},
+			wantError: true,
+		},
+		{
+			name:      "UnsupportedFormat",
+			format:    Format(-1),
+			samples:   [][]byte{[]byte(`{"id":1}`)},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ds, err := Infer(tt.format, true, tt.samples...)
+			if err != nil {
+				if !tt.wantError {
+					t.Fatalf("unexpected error is returned from Infer: %v", err)
+				}
+				return
+			} else if tt.wantError {
+				t.Fatalf("error is expected but it does not occur from Infer. samples: %q", tt.samples)
+			}
+
+			if d := cmp.Diff(ds.Definition(), tt.wantDefinition); d != "" {
+				t.Errorf("mismatch Definition: (-got +want)\n%s", d)
+			}
+
+			// all samples can be decoded into the inferred DynamicStruct
+			for i, sample := range tt.samples {
+				if _, _, err := DecodeInto(ds, tt.format, sample); err != nil {
+					t.Errorf("unexpected error is returned from DecodeInto sample %d: %v", i, err)
+				}
+			}
+		})
+	}
+}
+
+func TestInferWithFieldNameFunc(t *testing.T) {
+	t.Parallel()
+
+	ds, err := InferWithFieldNameFunc(FormatJSON, true, CamelCaseFieldName, []byte(`{"user_id":1}`), []byte(`{"userId":2}`))
+	if err != nil {
+		t.Fatalf("unexpected error is returned from InferWithFieldNameFunc: %v", err)
+	}
+
+	want := `type DynamicStruct struct {
+	UserId *int64 ` + "`json:\"userId,omitempty\"`" + `
+	UserId2 *int64 ` + "`json:\"user_id,omitempty\"`" + `
+}`
+	if d := cmp.Diff(ds.Definition(), want); d != "" {
+		t.Errorf("mismatch Definition: (-got +want)\n%s", d)
+	}
+}
+
+func TestDecodeInto(t *testing.T) {
+	t.Parallel()
+
+	ds, err := Infer(FormatJSON, true, []byte(`{"id":1,"user":{"name":"a"},"items":[{"sku":"x"}]}`))
+	if err != nil {
+		t.Fatalf("unexpected error is returned from Infer: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		data        []byte
+		wantLen     int
+		wantUnknown []string
+		wantError   bool
+	}{
+		{
+			name:    "Known",
+			data:    []byte(`{"id":2,"user":{"name":"b"},"items":[]}`),
+			wantLen: -1,
+		},
+		{
+			name:        "Unknown",
+			data:        []byte(`{"id":2,"user":{"name":"b","age":20},"items":[{"sku":"y","qty":1},{"qty":2}],"extra":{"a":1}}`),
+			wantLen:     -1,
+			wantUnknown: []string{"extra", "items.qty", "user.age"},
+		},
+		{
+			name:        "Array",
+			data:        []byte(`[{"id":1},{"id":2,"extra":true}]`),
+			wantLen:     2,
+			wantUnknown: []string{"extra"},
+		},
+		{
+			name:      "NotObject",
+			data:      []byte(`"abc"`),
+			wantError: true,
+		},
+		{
+			name:      "TypeMismatch",
+			data:      []byte(`{"id":"abc"}`),
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			v, unknown, err := DecodeInto(ds, FormatJSON, tt.data)
+			if err != nil {
+				if !tt.wantError {
+					t.Fatalf("unexpected error is returned from DecodeInto: %v", err)
+				}
+				return
+			} else if tt.wantError {
+				t.Fatalf("error is expected but it does not occur from DecodeInto. data: %s", tt.data)
+			}
+
+			rv := reflect.ValueOf(v).Elem()
+			if tt.wantLen >= 0 {
+				if rv.Len() != tt.wantLen {
+					t.Errorf("unexpected length. got: %d, want: %d", rv.Len(), tt.wantLen)
+				}
+			} else if rv.Type() != ds.Type() {
+				t.Errorf("unexpected type. got: %v, want: %v", rv.Type(), ds.Type())
+			}
+
+			if d := cmp.Diff(unknown, tt.wantUnknown); d != "" {
+				t.Errorf("mismatch unknown keys: (-got +want)\n%s", d)
+			}
+		})
+	}
+}
+
+func TestDecodeToGetter(t *testing.T) {
+	t.Parallel()
+
+	ds, err := Infer(FormatYAML, true, []byte("name: a\nuser:\n  id: 1\n"))
+	if err != nil {
+		t.Fatalf("unexpected error is returned from Infer: %v", err)
+	}
+
+	g, unknown, err := DecodeToGetter(ds, FormatYAML, []byte("name: b\nuser:\n  id: 2\n  role: admin\n"))
+	if err != nil {
+		t.Fatalf("unexpected error is returned from DecodeToGetter: %v", err)
+	}
+
+	if name, _ := g.String("Name"); name != "b" {
+		t.Errorf("unexpected Name: %s", name)
+	}
+	ug, ok := g.GetGetter("User")
+	if !ok {
+		t.Fatalf("User is not a struct: %#v", g.ToMap()["User"])
+	}
+	if id, _ := ug.Int("ID"); id != 2 {
+		t.Errorf("unexpected User.ID: %d", id)
+	}
+	if d := cmp.Diff(unknown, []string{"user.role"}); d != "" {
+		t.Errorf("mismatch unknown keys: (-got +want)\n%s", d)
+	}
+
+	if _, _, err := DecodeToGetter(ds, FormatYAML, []byte("- name: c\n")); err == nil {
+		t.Errorf("error is expected for top-level array")
+	}
+}
@@ -0,0 +1,43 @@
+package decoder
+
+import (
+	"strconv"
+
+	"github.com/iancoleman/strcase"
+
+	"github.com/goldeneggg/structil/dynamicstruct"
+)
+
+// FieldNameFunc returns the field name of DynamicStruct for a key of the decoded data.
+// The returned name should be an exported Go identifier.
+type FieldNameFunc func(key string) string
+
+var (
+	// GoFieldName converts a key to a field name that follows the Go naming conventions (the default).
+	// See dynamicstruct.ExportedName for the details (e.g. "user_id" to "UserID" and "@type" to "Type").
+	GoFieldName FieldNameFunc = dynamicstruct.ExportedName
+
+	// CamelCaseFieldName converts a key to UpperCamelCase (e.g. "user_id" to "UserId").
+	// Keys must not have characters that can not be used in identifiers.
+	CamelCaseFieldName FieldNameFunc = strcase.ToCamel
+)
+
+// keySet is the set of keys of objects.
+type keySet map[string]struct{}
+
+// uniqueFieldName returns name, or name with the smallest number suffix (from 2) that does not exist yet.
+// "Field" is used if name is empty.
+func uniqueFieldName(exists func(name string) bool, name string) string {
+	if name == "" {
+		name = "Field"
+	}
+	if !exists(name) {
+		return name
+	}
+
+	for i := 2; ; i++ {
+		if n := name + strconv.Itoa(i); !exists(n) {
+			return n
+		}
+	}
+}
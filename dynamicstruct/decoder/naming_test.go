@@ -0,0 +1,88 @@
+package decoder_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/goldeneggg/structil"
+	. "github.com/goldeneggg/structil/dynamicstruct/decoder"
+)
+
+func TestSetFieldNameFunc(t *testing.T) {
+	t.Parallel()
+
+	data := []byte(`{"foo_bar":1,"fooBar":2,"user_id":3,"@":4,"#":5}`)
+
+	tests := []struct {
+		name           string
+		fieldName      FieldNameFunc
+		wantDefinition string
+	}{
+		{
+			name: "Default",
+			wantDefinition: `type DynamicStruct struct {
+	Field int64 ` + "`json:\"#\"`" + `
+	Field2 int64 ` + "`json:\"@\"`" + `
+	FooBar int64 ` + "`json:\"fooBar\"`" + `
+	FooBar2 int64 ` + "`json:\"foo_bar\"`" + `
+	UserID int64 ` + "`json:\"user_id\"`" + `
+}`,
+		},
+		{
+			name: "Custom",
+			fieldName: func(key string) string {
+				return "K" + GoFieldName(key)
+			},
+			wantDefinition: `type DynamicStruct struct {
+	K int64 ` + "`json:\"#\"`" + `
+	K2 int64 ` + "`json:\"@\"`" + `
+	KFooBar int64 ` + "`json:\"fooBar\"`" + `
+	KFooBar2 int64 ` + "`json:\"foo_bar\"`" + `
+	KUserID int64 ` + "`json:\"user_id\"`" + `
+}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			dec, err := FromJSON(data)
+			if err != nil {
+				t.Fatalf("unexpected error is returned from FromJSON: %v", err)
+			}
+			if tt.fieldName != nil {
+				dec = dec.SetFieldNameFunc(tt.fieldName)
+			}
+
+			ds, err := dec.DynamicStruct(true, true)
+			if err != nil {
+				t.Fatalf("unexpected error is returned from DynamicStruct: %v", err)
+			}
+			if d := cmp.Diff(ds.Definition(), tt.wantDefinition); d != "" {
+				t.Errorf("mismatch Definition: (-got +want)\n%s", d)
+			}
+
+			// the values are decoded by the original keys in the tags
+			sp, err := dec.DecodeToSlice(ds)
+			if err != nil {
+				t.Fatalf("unexpected error is returned from DecodeToSlice: %v", err)
+			}
+			g, err := structil.NewGetter(reflect.ValueOf(sp).Elem().Index(0).Interface())
+			if err != nil {
+				t.Fatalf("unexpected error is returned from NewGetter: %v", err)
+			}
+			var got []int64
+			for _, name := range g.Names() {
+				i, _ := g.Int64(name)
+				got = append(got, i)
+			}
+			if d := cmp.Diff(got, []int64{5, 4, 2, 1, 3}); d != "" {
+				t.Errorf("mismatch values: (-got +want)\n%s", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package decoder
+
+import (
+	"encoding/json"
+	"reflect"
+	"strconv"
+)
+
+// numKind is the inferred kind of JSON numbers.
+type numKind int
+
+const (
+	numNone      numKind = iota
+	numNonNegInt         // fits in both int64 and uint64
+	numInt               // fits in int64
+	numUint              // fits in only uint64
+	numFloat
+)
+
+var (
+	int64Type   = reflect.TypeOf(int64(0))
+	uint64Type  = reflect.TypeOf(uint64(0))
+	float64Type = reflect.TypeOf(float64(0))
+)
+
+func kindOfNumber(n json.Number) numKind {
+	if i, err := n.Int64(); err == nil {
+		if i < 0 {
+			return numInt
+		}
+		return numNonNegInt
+	}
+	if _, err := strconv.ParseUint(n.String(), 10, 64); err == nil {
+		return numUint
+	}
+	return numFloat
+}
+
+// widen returns the kind that can hold the numbers of both k and o.
+func (k numKind) widen(o numKind) numKind {
+	switch {
+	case k == o || o == numNone:
+		return k
+	case k == numNone:
+		return o
+	case k == numNonNegInt && (o == numInt || o == numUint):
+		return o
+	case o == numNonNegInt && (k == numInt || k == numUint):
+		return k
+	}
+
+	// e.g. negative int64 and uint64 over math.MaxInt64
+	return numFloat
+}
+
+func (k numKind) typ() reflect.Type {
+	switch k {
+	case numNonNegInt, numInt:
+		return int64Type
+	case numUint:
+		return uint64Type
+	}
+	return float64Type
+}
+
+// collectNumKinds widens the kinds of numbers in v by the dot separated paths.
+// The elements of arrays (including the top-level array) share the path of the array.
+func collectNumKinds(v interface{}, path string, kinds map[string]numKind) {
+	switch t := v.(type) {
+	case json.Number:
+		kinds[path] = kinds[path].widen(kindOfNumber(t))
+	case map[string]interface{}:
+		for k, vv := range t {
+			collectNumKinds(vv, joinPath(path, k), kinds)
+		}
+	case []interface{}:
+		for _, e := range t {
+			collectNumKinds(e, path, kinds)
+		}
+	}
+}
+
+func joinPath(path string, k string) string {
+	if path == "" {
+		return k
+	}
+	return path + "." + k
+}
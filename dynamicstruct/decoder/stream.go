@@ -0,0 +1,176 @@
+package decoder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/goldeneggg/structil"
+	"github.com/goldeneggg/structil/dynamicstruct"
+)
+
+// Format is the data format for StreamDecoder, Infer and DecodeInto.
+type Format int
+
+const (
+	// FormatJSON is JSON.
+	// StreamDecoder reads newline delimited JSON (NDJSON), and whitespace separated JSON values are also accepted.
+	FormatJSON Format = iota
+
+	// FormatYAML is YAML.
+	// StreamDecoder reads multi-document YAML separated by "---".
+	FormatYAML
+
+	// FormatTOML is TOML. StreamDecoder does not support this.
+	FormatTOML
+
+	// FormatXML is XML. StreamDecoder does not support this.
+	FormatXML
+)
+
+func (f Format) dataType() (dataType, error) {
+	switch f {
+	case FormatJSON:
+		return typeJSON, nil
+	case FormatYAML:
+		return typeYAML, nil
+	case FormatTOML:
+		return typeTOML, nil
+	case FormatXML:
+		return typeXML, nil
+	}
+	return end, fmt.Errorf("unsupported format: %d", f)
+}
+
+// StreamDecoder is the decoder that reads documents (records) one at a time from io.Reader.
+// Only the current record is held in memory.
+type StreamDecoder struct {
+	dt   dataType
+	next func(v interface{}) error
+	nest bool
+	n    int // number of the decoded records
+	ds   *dynamicstruct.DynamicStruct
+
+	numKinds  map[string]numKind           // widened kinds of JSON numbers by field paths of the decoded records
+	elemTypes map[string]reflect.Type      // widened element types of non-empty arrays by field paths of the decoded records
+	keys      map[string]keySet            // keys of objects by paths of the decoded records
+	names     map[string]map[string]string // field names of keys by paths of objects
+	fieldName FieldNameFunc                // converts keys to field names
+}
+
+// NewStreamDecoder returns a StreamDecoder that reads the documents of format from r.
+func NewStreamDecoder(r io.Reader, format Format) (*StreamDecoder, error) {
+	sd := &StreamDecoder{
+		nest:      true,
+		numKinds:  make(map[string]numKind),
+		elemTypes: make(map[string]reflect.Type),
+		keys:      make(map[string]keySet),
+		names:     make(map[string]map[string]string),
+		fieldName: GoFieldName,
+	}
+
+	switch format {
+	case FormatJSON:
+		dec := json.NewDecoder(r)
+		dec.UseNumber()
+		sd.dt = typeJSON
+		sd.next = dec.Decode
+	case FormatYAML:
+		dec := yaml.NewDecoder(r)
+		sd.dt = typeYAML
+		sd.next = dec.Decode
+	default:
+		return nil, fmt.Errorf("unsupported format for StreamDecoder: %d", format)
+	}
+
+	return sd, nil
+}
+
+// SetNest sets whether nested objects are decoded as nested DynamicStructs (the default) or maps.
+func (sd *StreamDecoder) SetNest(nest bool) *StreamDecoder {
+	sd.nest = nest
+	return sd
+}
+
+// SetFieldNameFunc sets the function that converts keys to field names. The default is GoFieldName.
+// It must be called before the first record is decoded.
+// The names are assigned to the new keys of each record in sorted order, and the names of the keys in the previous records
+// are kept. So if some keys are converted to the same name, the number suffix is added to the name of the key
+// that appears later (e.g. "FooBar" for "foo_bar" in the 1st record and "FooBar2" for "fooBar" in the 2nd record).
+func (sd *StreamDecoder) SetFieldNameFunc(f FieldNameFunc) *StreamDecoder {
+	sd.fieldName = f
+	return sd
+}
+
+// Next decodes the next record and returns a structil.Getter of it.
+// io.EOF is returned if there are no more records. Empty YAML documents are skipped.
+//
+// The DynamicStruct is evolved by each record: the fields of the new keys are added as optional fields
+// and the conflicted types are widened (see dynamicstruct.Merge).
+// The kinds of numbers are widened across the records like the elements of a single top-level array
+// (e.g. int64 and uint64 over math.MaxInt64 to uint64).
+// The record is decoded into the evolved DynamicStruct, so the Getters of the records may have different types.
+func (sd *StreamDecoder) Next() (*structil.Getter, error) {
+	var v interface{}
+	for v == nil {
+		if err := sd.next(&v); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, err
+			}
+			return nil, fmt.Errorf("fail to decode record %d: %w", sd.n+1, err)
+		}
+	}
+	sd.n++
+
+	if _, ok := v.(map[string]interface{}); !ok {
+		return nil, fmt.Errorf("record %d is not an object: %T", sd.n, v)
+	}
+
+	d, err := newDecoderFromIntf(nil, v, sd.dt)
+	if err != nil {
+		return nil, err
+	}
+	// the kinds of numbers are widened across the records like the elements of a single top-level array
+	collectNumKinds(v, "", sd.numKinds)
+	d.numKinds = sd.numKinds
+	d.elemTypes = sd.elemTypes
+	d.collectElemTypes(v, "")
+	// the field names of keys are kept across the records
+	d.keys = sd.keys
+	d.names = sd.names
+	d.fieldName = sd.fieldName
+	d.collectKeys(v, "")
+
+	ds, err := d.DynamicStruct(sd.nest, true)
+	if err != nil {
+		return nil, fmt.Errorf("fail to infer record %d: %w", sd.n, err)
+	}
+
+	if sd.ds == nil {
+		sd.ds = ds
+	} else {
+		sd.ds, err = d.merge(sd.ds, ds)
+		if err != nil {
+			return nil, fmt.Errorf("fail to merge record %d: %w", sd.n, err)
+		}
+		if sd.ds, err = d.retypeNumbers(sd.ds); err != nil {
+			return nil, fmt.Errorf("fail to merge record %d: %w", sd.n, err)
+		}
+	}
+
+	dsi, err := d.decodeToDynamicStruct(sd.ds)
+	if err != nil {
+		return nil, fmt.Errorf("fail to decode record %d: %w", sd.n, err)
+	}
+
+	return structil.NewGetter(dsi)
+}
+
+// DynamicStruct returns the DynamicStruct evolved by the decoded records, or nil if no records are decoded.
+func (sd *StreamDecoder) DynamicStruct() *dynamicstruct.DynamicStruct {
+	return sd.ds
+}
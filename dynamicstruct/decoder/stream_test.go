@@ -0,0 +1,240 @@
+package decoder_test
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	. "github.com/goldeneggg/structil/dynamicstruct/decoder"
+)
+
+func TestStreamDecoder(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		data           string
+		format         Format
+		wantNames      []string
+		wantDefinition string
+		wantError      bool
+	}{
+		{
+			name: "NDJSON",
+			data: `{"name":"a","id":1}
+
+{"name":"b","id":2.5,"tags":["x"]}
+{"name":"c","obj":{"k":"v"}}
+`,
+			format:    FormatJSON,
+			wantNames: []string{"a", "b", "c"},
+			wantDefinition: `type DynamicStruct struct {
+	ID *float64 ` + "`json:\"id,omitempty\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+	Obj *struct {
+		K string ` + "`json:\"k\"`" + `
+	} ` + "`json:\"obj,omitempty\"`" + `
+	Tags []string ` + "`json:\"tags,omitempty\"`" + `
+}`,
+		},
+		{
+			// non-negative int64 and uint64 over math.MaxInt64 are widened to uint64 like a single top-level array
+			name: "NDJSONWidenedUnsignedIntegers",
+			data: `{"name":"a","id":5}
+{"name":"b","id":18446744073709551615,"scores":[1]}
+{"name":"c","scores":[18446744073709551615]}
+`,
+			format:    FormatJSON,
+			wantNames: []string{"a", "b", "c"},
+			wantDefinition: `type DynamicStruct struct {
+	ID *uint64 ` + "`json:\"id,omitempty\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+	Scores []uint64 ` + "`json:\"scores,omitempty\"`" + `
+}`,
+		},
+		{
+			// negative int64 and uint64 over math.MaxInt64 are widened to float64
+			name: "NDJSONWidenedIntegers",
+			data: `{"name":"a","id":-5}
+{"name":"b","id":18446744073709551615}
+`,
+			format:    FormatJSON,
+			wantNames: []string{"a", "b"},
+			wantDefinition: `type DynamicStruct struct {
+	ID float64 ` + "`json:\"id\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+}`,
+		},
+		{
+			name: "MultiDocumentYAML",
+			data: `name: a
+---
+---
+name: b
+count: 3
+`,
+			format:    FormatYAML,
+			wantNames: []string{"a", "b"},
+			wantDefinition: `type DynamicStruct struct {
+	Count *int ` + "`yaml:\"count,omitempty\"`" + `
+	Name string ` + "`yaml:\"name\"`" + `
+}`,
+		},
+		{
+			name:      "NotObject",
+			data:      `{"name":"a"} [1,2]`,
+			format:    FormatJSON,
+			wantNames: []string{"a"},
+			wantError: true,
+		},
+		{
+			name:      "Invalid",
+			data:      `{"name":"a"} {`,
+			format:    FormatJSON,
+			wantNames: []string{"a"},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			sd, err := NewStreamDecoder(strings.NewReader(tt.data), tt.format)
+			if err != nil {
+				t.Fatalf("unexpected error is returned from NewStreamDecoder: %v", err)
+			}
+
+			var names []string
+			for {
+				g, err := sd.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					if !tt.wantError {
+						t.Fatalf("unexpected error is returned from Next: %v", err)
+					}
+					break
+				}
+
+				name, _ := g.String("Name")
+				names = append(names, name)
+			}
+
+			if d := cmp.Diff(names, tt.wantNames); d != "" {
+				t.Errorf("mismatch Name: (-got +want)\n%s", d)
+			}
+			if tt.wantError {
+				return
+			}
+			if d := cmp.Diff(sd.DynamicStruct().Definition(), tt.wantDefinition); d != "" {
+				t.Errorf("mismatch Definition: (-got +want)\n%s", d)
+			}
+		})
+	}
+}
+
+func TestStreamDecoderUint64(t *testing.T) {
+	t.Parallel()
+
+	sd, err := NewStreamDecoder(strings.NewReader(`{"id":5}`+"\n"+`{"id":18446744073709551615}`), FormatJSON)
+	if err != nil {
+		t.Fatalf("unexpected error is returned from NewStreamDecoder: %v", err)
+	}
+
+	var ids []interface{}
+	for {
+		g, err := sd.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("unexpected error is returned from Next: %v", err)
+		}
+		id, _ := g.Get("ID")
+		ids = append(ids, id)
+	}
+
+	// the values are not rounded to float64
+	if d := cmp.Diff(ids, []interface{}{int64(5), uint64(18446744073709551615)}); d != "" {
+		t.Errorf("mismatch ID: (-got +want)\n%s", d)
+	}
+}
+
+func TestStreamDecoderConflictedKeys(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		fieldName FieldNameFunc
+		wantNames []string
+	}{
+		{
+			name:      "GoFieldName",
+			wantNames: []string{"FooBar", "FooBar2", "UserID"},
+		},
+		{
+			name:      "CamelCaseFieldName",
+			fieldName: CamelCaseFieldName,
+			wantNames: []string{"FooBar", "FooBar2", "UserId"},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			data := `{"foo_bar":1}` + "\n" + `{"fooBar":2,"user_id":3}` + "\n" + `{"foo_bar":4,"fooBar":5}`
+			sd, err := NewStreamDecoder(strings.NewReader(data), FormatJSON)
+			if err != nil {
+				t.Fatalf("unexpected error is returned from NewStreamDecoder: %v", err)
+			}
+			if tt.fieldName != nil {
+				sd = sd.SetFieldNameFunc(tt.fieldName)
+			}
+
+			// keys converted to the same name in different records are not merged into one field
+			var got []map[string]interface{}
+			for {
+				g, err := sd.Next()
+				if errors.Is(err, io.EOF) {
+					break
+				}
+				if err != nil {
+					t.Fatalf("unexpected error is returned from Next: %v", err)
+				}
+				got = append(got, g.ToMap())
+			}
+
+			want := []map[string]interface{}{
+				{tt.wantNames[0]: int64(1)},
+				{tt.wantNames[0]: nil, tt.wantNames[1]: int64(2), tt.wantNames[2]: int64(3)},
+				{tt.wantNames[0]: int64(4), tt.wantNames[1]: int64(5), tt.wantNames[2]: nil},
+			}
+			if d := cmp.Diff(got, want); d != "" {
+				t.Errorf("mismatch values: (-got +want)\n%s", d)
+			}
+			var names []string
+			for _, f := range sd.DynamicStruct().Fields() {
+				names = append(names, f.Name)
+			}
+			if d := cmp.Diff(names, tt.wantNames); d != "" {
+				t.Errorf("mismatch field names: (-got +want)\n%s", d)
+			}
+		})
+	}
+}
+
+func TestNewStreamDecoderError(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewStreamDecoder(strings.NewReader(""), Format(-1)); err == nil {
+		t.Errorf("error is expected for unsupported format")
+	}
+}
@@ -0,0 +1,225 @@
+package decoder
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Keys of the string key map for XML.
+// An element is converted to a string (text only) or a map whose keys are the local names of child elements,
+// the local names of attributes with xmlAttrPrefix and xmlTextKey for the text content.
+const (
+	xmlAttrPrefix = "-"
+	xmlTextKey    = "#text"
+	xmlRootName   = "root"
+)
+
+// unmarshalXML converts the root element of XML data to a map[string]interface{}.
+// Repeated elements are converted to []interface{}. Namespace declarations are ignored.
+func unmarshalXML(data []byte) (interface{}, error) {
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		tok, err := dec.Token()
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("root element is not found in XML")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		if se, ok := tok.(xml.StartElement); ok {
+			v, err := decodeXMLElement(dec, se)
+			if err != nil {
+				return nil, err
+			}
+
+			switch t := v.(type) {
+			case map[string]interface{}:
+				return t, nil
+			case string:
+				if strings.TrimSpace(t) == "" {
+					return map[string]interface{}{}, nil
+				}
+				return map[string]interface{}{xmlTextKey: t}, nil
+			}
+		}
+	}
+}
+
+func decodeXMLElement(dec *xml.Decoder, se xml.StartElement) (interface{}, error) {
+	m := make(map[string]interface{})
+	for _, a := range se.Attr {
+		if a.Name.Space == "xmlns" || a.Name.Local == "xmlns" {
+			continue
+		}
+		m[xmlAttrPrefix+a.Name.Local] = a.Value
+	}
+
+	var text strings.Builder
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.StartElement:
+			v, err := decodeXMLElement(dec, t)
+			if err != nil {
+				return nil, err
+			}
+			addXMLValue(m, t.Name.Local, v)
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			// text only element (e.g. "<name>abc</name>" or "<name/>")
+			if len(m) == 0 {
+				return text.String(), nil
+			}
+
+			if s := strings.TrimSpace(text.String()); s != "" {
+				m[xmlTextKey] = s
+			}
+			normalizeXMLSlices(m)
+			return m, nil
+		}
+	}
+}
+
+func addXMLValue(m map[string]interface{}, k string, v interface{}) {
+	switch cur := m[k].(type) {
+	case nil:
+		m[k] = v
+	case []interface{}:
+		m[k] = append(cur, v)
+	default:
+		m[k] = []interface{}{cur, v}
+	}
+}
+
+// normalizeXMLSlices converts text only elements to maps in the repeated elements that have maps
+// (e.g. "<a>x</a><a id="1">y</a>"), so that all elements are decoded to the same struct type.
+func normalizeXMLSlices(m map[string]interface{}) {
+	for _, v := range m {
+		es, ok := v.([]interface{})
+		if !ok {
+			continue
+		}
+
+		hasMap := false
+		for _, e := range es {
+			if _, ok := e.(map[string]interface{}); ok {
+				hasMap = true
+				break
+			}
+		}
+		if !hasMap {
+			continue
+		}
+
+		for i, e := range es {
+			if s, ok := e.(string); ok {
+				es[i] = map[string]interface{}{xmlTextKey: s}
+			}
+		}
+	}
+}
+
+// marshalXML converts a map[string]interface{} converted by unmarshalXML to XML data.
+// The name of the root element is xmlRootName.
+func marshalXML(v interface{}) ([]byte, error) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unsupported type [%T] for XML root element", v)
+	}
+
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	if err := encodeXMLElement(enc, xmlRootName, m); err != nil {
+		return nil, err
+	}
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func encodeXMLElement(enc *xml.Encoder, name string, v interface{}) error {
+	se := xml.StartElement{Name: xml.Name{Local: name}}
+
+	switch t := v.(type) {
+	case []interface{}:
+		for _, e := range t {
+			if err := encodeXMLElement(enc, name, e); err != nil {
+				return err
+			}
+		}
+		return nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		var children []string
+		for _, k := range keys {
+			switch {
+			case strings.HasPrefix(k, xmlAttrPrefix):
+				se.Attr = append(se.Attr, xml.Attr{Name: xml.Name{Local: k[len(xmlAttrPrefix):]}, Value: fmt.Sprint(t[k])})
+			case k != xmlTextKey:
+				children = append(children, k)
+			}
+		}
+
+		if err := enc.EncodeToken(se); err != nil {
+			return err
+		}
+		if s, ok := t[xmlTextKey]; ok {
+			if err := enc.EncodeToken(xml.CharData(fmt.Sprint(s))); err != nil {
+				return err
+			}
+		}
+		for _, k := range children {
+			if err := encodeXMLElement(enc, k, t[k]); err != nil {
+				return err
+			}
+		}
+	default:
+		if err := enc.EncodeToken(se); err != nil {
+			return err
+		}
+		if err := enc.EncodeToken(xml.CharData(fmt.Sprint(t))); err != nil {
+			return err
+		}
+	}
+
+	return enc.EncodeToken(se.End())
+}
+
+// xmlFieldName returns the field name, the tag name and the tag options for the key k of an element that has keys.
+func xmlFieldName(keys keySet, k string, fieldName FieldNameFunc) (string, string, []string) {
+	switch {
+	case k == xmlTextKey:
+		if _, ok := keys["text"]; ok {
+			return "Chardata", "", []string{"chardata"}
+		}
+		return "Text", "", []string{"chardata"}
+	case strings.HasPrefix(k, xmlAttrPrefix):
+		an := k[len(xmlAttrPrefix):]
+		name := fieldName(an)
+		// avoid the conflict with the child element of the same name
+		if _, ok := keys[an]; ok {
+			name += "Attr"
+		}
+		return name, an, []string{"attr"}
+	}
+
+	return fieldName(k), k, nil
+}
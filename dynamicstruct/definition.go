@@ -0,0 +1,238 @@
+package dynamicstruct
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// FromDefinition returns a DynamicStruct built from the Go source of struct type declarations.
+// This is the inverse of DynamicStruct.Definition and DynamicStruct.GoSource.
+//
+// src can be a whole source file or only type declarations (e.g. "type X struct {...}").
+// The first type declaration is the root struct, and the other declarations can be referred from it.
+// Supported types are predeclared types, pointers, slices, arrays, maps, anonymous structs, interface{}
+// and the named types registered by RegisterSchemaType (e.g. time.Time).
+// The returned DynamicStruct is built by pointer-mode like Build().
+func FromDefinition(src string) (*DynamicStruct, error) {
+	specs, err := parseTypeSpecs(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no type declaration is found")
+	}
+
+	p := &definitionParser{
+		specs:    make(map[string]*ast.TypeSpec, len(specs)),
+		resolved: make(map[string]reflect.Type),
+		visiting: make(map[string]bool),
+	}
+	for _, spec := range specs {
+		if _, ok := p.specs[spec.Name.Name]; ok {
+			return nil, fmt.Errorf("type %s is declared more than once", spec.Name.Name)
+		}
+		p.specs[spec.Name.Name] = spec
+	}
+
+	root := specs[0]
+	st, ok := root.Type.(*ast.StructType)
+	if !ok {
+		return nil, fmt.Errorf("type %s is not a struct type", root.Name.Name)
+	}
+
+	b := NewBuilder().SetStructName(root.Name.Name)
+	p.visiting[root.Name.Name] = true
+	fields, err := p.fields(st, "")
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range fields {
+		b.AddTypeWithTag(f.Name, f.Type, string(f.Tag))
+	}
+
+	return b.Build()
+}
+
+func parseTypeSpecs(src string) ([]*ast.TypeSpec, error) {
+	fset := token.NewFileSet()
+	if _, err := parser.ParseFile(fset, "", src, parser.PackageClauseOnly); err != nil {
+		// src does not have a package clause
+		src = "package p\n" + src
+	}
+	f, err := parser.ParseFile(fset, "", src, 0)
+	if err != nil {
+		return nil, fmt.Errorf("fail to parse definition: %w", err)
+	}
+
+	var specs []*ast.TypeSpec
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			specs = append(specs, spec.(*ast.TypeSpec))
+		}
+	}
+
+	return specs, nil
+}
+
+type definitionParser struct {
+	specs    map[string]*ast.TypeSpec
+	resolved map[string]reflect.Type
+	visiting map[string]bool
+}
+
+func (p *definitionParser) fields(st *ast.StructType, path string) ([]reflect.StructField, error) {
+	var fields []reflect.StructField
+	for _, f := range st.Fields.List {
+		if len(f.Names) == 0 {
+			return nil, fmt.Errorf("field %s: embedded fields are not supported", joinPath(path, exprString(f.Type)))
+		}
+
+		var tag string
+		if f.Tag != nil {
+			var err error
+			tag, err = strconv.Unquote(f.Tag.Value)
+			if err != nil {
+				return nil, fmt.Errorf("field %s: invalid tag %s", joinPath(path, f.Names[0].Name), f.Tag.Value)
+			}
+		}
+
+		for _, n := range f.Names {
+			fp := joinPath(path, n.Name)
+			typ, err := p.typeOf(f.Type, fp)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, reflect.StructField{Name: n.Name, Type: typ, Tag: reflect.StructTag(tag)})
+		}
+	}
+
+	return fields, nil
+}
+
+func (p *definitionParser) typeOf(expr ast.Expr, path string) (reflect.Type, error) {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return p.identType(e.Name, path)
+	case *ast.SelectorExpr:
+		name := exprString(e)
+		t, ok := lookupNamedSchemaType(name)
+		if !ok {
+			return nil, fmt.Errorf("field %s: unsupported type %s", path, name)
+		}
+		return t, nil
+	case *ast.StarExpr:
+		et, err := p.typeOf(e.X, path)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.PtrTo(et), nil
+	case *ast.ArrayType:
+		et, err := p.typeOf(e.Elt, path)
+		if err != nil {
+			return nil, err
+		}
+		if e.Len == nil {
+			return reflect.SliceOf(et), nil
+		}
+		lit, ok := e.Len.(*ast.BasicLit)
+		if !ok || lit.Kind != token.INT {
+			return nil, fmt.Errorf("field %s: array length must be an integer literal", path)
+		}
+		n, err := strconv.Atoi(lit.Value)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: invalid array length %s", path, lit.Value)
+		}
+		return reflect.ArrayOf(n, et), nil
+	case *ast.MapType:
+		kt, err := p.typeOf(e.Key, path)
+		if err != nil {
+			return nil, err
+		}
+		if !kt.Comparable() {
+			return nil, fmt.Errorf("field %s: invalid map key type %v", path, kt)
+		}
+		vt, err := p.typeOf(e.Value, path)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.MapOf(kt, vt), nil
+	case *ast.StructType:
+		fields, err := p.fields(e, path)
+		if err != nil {
+			return nil, err
+		}
+		t, err := structOf(fields)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", path, err)
+		}
+		return t, nil
+	case *ast.InterfaceType:
+		if len(e.Methods.List) > 0 {
+			return nil, fmt.Errorf("field %s: unsupported non-empty interface", path)
+		}
+		return interfaceType, nil
+	case *ast.ParenExpr:
+		return p.typeOf(e.X, path)
+	}
+
+	return nil, fmt.Errorf("field %s: unsupported type %s", path, exprString(expr))
+}
+
+func (p *definitionParser) identType(name string, path string) (reflect.Type, error) {
+	if spec, ok := p.specs[name]; ok {
+		if t, ok := p.resolved[name]; ok {
+			return t, nil
+		}
+		if p.visiting[name] {
+			return nil, fmt.Errorf("field %s: recursive type %s is not supported", path, name)
+		}
+
+		p.visiting[name] = true
+		t, err := p.typeOf(spec.Type, path)
+		delete(p.visiting, name)
+		if err != nil {
+			return nil, err
+		}
+		p.resolved[name] = t
+		return t, nil
+	}
+
+	switch name {
+	case "byte":
+		return schemaKinds[reflect.Uint8.String()], nil
+	case "rune":
+		return schemaKinds[reflect.Int32.String()], nil
+	case "any":
+		return interfaceType, nil
+	case reflect.Interface.String():
+		// "interface" is a kind name but not a type name
+	default:
+		if t, ok := schemaKinds[name]; ok {
+			return t, nil
+		}
+	}
+
+	return nil, fmt.Errorf("field %s: unsupported type %s", path, name)
+}
+
+func exprString(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return exprString(e.X) + "." + e.Sel.Name
+	case *ast.StarExpr:
+		return "*" + exprString(e.X)
+	}
+
+	return strings.TrimPrefix(fmt.Sprintf("%T", expr), "*ast.")
+}
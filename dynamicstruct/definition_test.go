@@ -0,0 +1,203 @@
+package dynamicstruct_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	. "github.com/goldeneggg/structil/dynamicstruct"
+)
+
+func TestFromDefinition(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		src            string
+		wantName       string
+		wantDefinition string
+		wantError      bool
+	}{
+		{
+			name: "types and tags",
+			src: `type Sample struct {
+	A, B     int
+	Name     string ` + "`json:\"name\" yaml:\"name\"`" + `
+	Bytes    []byte
+	Fixed    [4]rune
+	Ptr      *float64
+	Attrs    map[string][]bool
+	Any      interface{}
+	Anything any
+	Created  time.Time
+	Timeout  *time.Duration
+	Nested   struct {
+		Key string ` + "`json:\"key\"`" + `
+	} ` + "`json:\"nested\"`" + `
+	Items []*struct{ V uint8 }
+}`,
+			wantName: "Sample",
+			wantDefinition: `type Sample struct {
+	A int
+	Any interface {}
+	Anything interface {}
+	Attrs map[string][]bool
+	B int
+	Bytes []uint8
+	Created time.Time
+	Fixed [4]int32
+	Items []*struct {
+		V uint8
+	}
+	Name string ` + "`json:\"name\" yaml:\"name\"`" + `
+	Nested struct {
+		Key string ` + "`json:\"key\"`" + `
+	} ` + "`json:\"nested\"`" + `
+	Ptr *float64
+	Timeout *time.Duration
+}`,
+		},
+		{
+			name: "source file with referred types",
+			src: `package model
+
+import "time"
+
+type Order struct {
+	ID    int64  ` + "`json:\"id\"`" + `
+	Items []Item ` + "`json:\"items\"`" + `
+	Meta  *Meta
+}
+
+type Item struct {
+	Code string
+	At   time.Time
+}
+
+type Meta = map[string]Item
+`,
+			wantName: "Order",
+			wantDefinition: `type Order struct {
+	ID int64 ` + "`json:\"id\"`" + `
+	Items []struct {
+		At time.Time
+		Code string
+	} ` + "`json:\"items\"`" + `
+	Meta *map[string]struct { Code string; At time.Time }
+}`,
+		},
+		{name: "syntax error", src: `type X struct {`, wantError: true},
+		{name: "no type", src: `var x int`, wantError: true},
+		{name: "not struct", src: `type X []int`, wantError: true},
+		{name: "unknown type", src: `type X struct { A Unknown }`, wantError: true},
+		{name: "unknown selector", src: `type X struct { A sync.Mutex }`, wantError: true},
+		{name: "embedded", src: `type X struct { Y }; type Y struct{}`, wantError: true},
+		{name: "recursive", src: `type X struct { Children []X }`, wantError: true},
+		{name: "mutual recursive", src: `type X struct { Y *Y }; type Y struct { X *X }`, wantError: true},
+		{name: "duplicated", src: `type X struct {}; type X struct {}`, wantError: true},
+		{name: "non-empty interface", src: `type X struct { E interface{ Error() string } }`, wantError: true},
+		{name: "invalid map key", src: `type X struct { M map[[]int]int }`, wantError: true},
+		{name: "func", src: `type X struct { F func() }`, wantError: true},
+		{name: "unexported field", src: `type X struct { f int }`, wantError: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ds, err := FromDefinition(tt.src)
+			if err != nil {
+				if !tt.wantError {
+					t.Fatalf("unexpected error is returned from FromDefinition: %v", err)
+				}
+				return
+			} else if tt.wantError {
+				t.Fatalf("error is expected but it does not occur from FromDefinition")
+			}
+
+			if ds.Name() != tt.wantName {
+				t.Errorf("unexpected name. got: %s, want: %s", ds.Name(), tt.wantName)
+			}
+			if d := cmp.Diff(ds.Definition(), tt.wantDefinition); d != "" {
+				t.Fatalf("mismatch Definition: (-got +want)\n%s", d)
+			}
+		})
+	}
+}
+
+func TestFromDefinitionSyntaxError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		src  string
+	}{
+		{name: "without package", src: `type X struct { A int`},
+		{name: "with package", src: "package p\ntype X struct { A int"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := FromDefinition(tt.src)
+			if err == nil {
+				t.Fatalf("error is expected but it does not occur from FromDefinition")
+			}
+			// the syntax error of the struct is reported, not the missing package clause
+			if strings.Contains(err.Error(), "expected 'package'") || !strings.Contains(err.Error(), "expected '}'") {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestFromDefinitionRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	// nested DynamicStructs built by Build() are added as pointers in slices
+	nds, err := NewBuilder().
+		AddStringWithTag("Key", `json:"key"`).
+		AddType("At", reflect.TypeOf(time.Time{})).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+	ds, err := NewBuilder().
+		SetStructName("Round").
+		AddStringWithTag("Name", `json:"name"`).
+		AddMap("Attrs", SampleString, SampleFloat64).
+		AddInterface("Any", false).
+		AddDynamicStructWithTag("Obj", nds, false, `json:"obj"`).
+		AddDynamicStructSlice("Objs", nds).
+		AddDynamicStructPtr("ObjPtr", nds).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	got, err := FromDefinition(ds.Definition())
+	if err != nil {
+		t.Fatalf("unexpected error is returned from FromDefinition: %v", err)
+	}
+	if !Equal(got, ds, EqualOptions{}) {
+		t.Errorf("Definition round trip is not equal.\ngot:\n%s\nwant:\n%s", got.Definition(), ds.Definition())
+	}
+
+	src, err := ds.GoSource(GoSourceOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error is returned from GoSource: %v", err)
+	}
+	got, err = FromDefinition(string(src))
+	if err != nil {
+		t.Fatalf("unexpected error is returned from FromDefinition: %v", err)
+	}
+	if !Equal(got, ds, EqualOptions{}) {
+		t.Errorf("GoSource round trip is not equal.\ngot:\n%s\nwant:\n%s", got.Definition(), ds.Definition())
+	}
+}
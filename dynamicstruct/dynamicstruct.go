@@ -15,6 +15,8 @@ type DynamicStruct struct {
 	fields []reflect.StructField
 	rt     reflect.Type
 	isPtr  bool
+	metas  map[string]FieldMeta // metadata keyed by field paths
+	refs   map[string]*Ref      // Refs keyed by field paths
 	// sortedFields  string  // TODO: for performance tuning
 	def string
 }
@@ -52,6 +54,7 @@ func (ds *DynamicStruct) NumField() int {
 }
 
 // Fields returns the all fields of the built struct.
+// Fields are ordered by added order in Builder.
 func (ds *DynamicStruct) Fields() []reflect.StructField {
 	return ds.fields
 }
@@ -73,9 +76,28 @@ func (ds *DynamicStruct) IsPtr() bool {
 	return ds.isPtr
 }
 
+// ToBuilder returns a new Builder that has the fields and the name of this.
+// The returned Builder can extend, rename, retag or remove fields without changing this.
+// Metadata and Refs of fields are also kept.
+func (ds *DynamicStruct) ToBuilder() *Builder {
+	b := NewBuilderFromType(ds.rt).SetStructName(ds.name)
+	for _, key := range b.keys {
+		bf := b.getFieldMap(key)
+		bf.meta = ds.metas[key]
+		bf.nestedMetas = subMetas(ds.metas, key)
+		bf.ref = ds.refs[key]
+		bf.nestedRefs = subRefs(ds.refs, key)
+	}
+
+	return b
+}
+
 // NewInterface returns the new interface value of built struct.
+// The default values in the metadata of fields are set. See FieldMeta.
+// Use NewInterfaceWithOptions for allocating nested pointers, empty slices and maps, and "default" tags.
 func (ds *DynamicStruct) NewInterface() interface{} {
 	rv := reflect.New(ds.rt)
+	applyDefaults(rv.Elem(), ds.metas)
 	if ds.isPtr {
 		return rv.Interface()
 	}
@@ -83,8 +105,41 @@ func (ds *DynamicStruct) NewInterface() interface{} {
 	return reflect.Indirect(rv).Interface()
 }
 
+// NewSlice returns a pointer to the new slice of built struct with length n and capacity c (e.g. *[]DynamicStruct).
+// The returned value can be used for unmarshaling arrays.
+func (ds *DynamicStruct) NewSlice(n int, c int) interface{} {
+	return newSliceOf(ds.rt, n, c)
+}
+
+// NewSliceOfPtr returns a pointer to the new slice of built struct pointers with length n and capacity c (e.g. *[]*DynamicStruct).
+// Elements of the returned slice are nil.
+func (ds *DynamicStruct) NewSliceOfPtr(n int, c int) interface{} {
+	return newSliceOf(reflect.PtrTo(ds.rt), n, c)
+}
+
+func newSliceOf(et reflect.Type, n int, c int) interface{} {
+	rv := reflect.New(reflect.SliceOf(et))
+	rv.Elem().Set(reflect.MakeSlice(rv.Elem().Type(), n, c))
+	return rv.Interface()
+}
+
+// NewMap returns a pointer to the new map whose key type is keyType and value type is built struct (e.g. *map[string]DynamicStruct).
+// The returned value can be used for unmarshaling objects whose values are same shape.
+// An error is returned if keyType is nil or not comparable.
+func (ds *DynamicStruct) NewMap(keyType reflect.Type) (interface{}, error) {
+	if keyType == nil || !keyType.Comparable() {
+		return nil, fmt.Errorf("invalid map key type [%v]", keyType)
+	}
+
+	rv := reflect.New(reflect.MapOf(keyType, ds.rt))
+	rv.Elem().Set(reflect.MakeMap(rv.Elem().Type()))
+	return rv.Interface(), nil
+}
+
 // Definition returns the struct definition string with field indention by TAB.
-// Fields are sorted by field name.
+// Fields are sorted by field name, and the Refs and the metadata of fields are rendered as trailing comments.
+// Nested structs are rendered inline with their pointers (e.g. "*struct {" and "[]*struct {"),
+// so FromDefinition returns a DynamicStruct that has the same field types.
 func (ds *DynamicStruct) Definition() string {
 	// build definition only once
 	if ds.def != "" {
@@ -92,13 +147,13 @@ func (ds *DynamicStruct) Definition() string {
 	}
 
 	var stb strings.Builder
-	ds.def = definition(&stb, ds.fields, ds.name, 1, "")
+	ds.def = definition(&stb, ds.fields, ds.name, 1, "", "", ds)
 	return ds.def
 }
 
-func definition(stbp *strings.Builder, flds []reflect.StructField, name string, indentLevel int, slicePrefix string) string {
+func definition(stbp *strings.Builder, flds []reflect.StructField, name string, indentLevel int, typePrefix string, path string, ds *DynamicStruct) string {
 	sortedFlds := sortFields(flds)
-	sp := slicePrefix
+	tp := typePrefix
 
 	if indentLevel == 1 {
 		stbp.WriteString("type ")
@@ -108,9 +163,9 @@ func definition(stbp *strings.Builder, flds []reflect.StructField, name string,
 		stbp.WriteString(name + " ")
 	}
 
-	// add "[]" if slice
-	if sp != "" {
-		stbp.WriteString(sp)
+	// add "[]", "*" or "[]*" if slice or pointer
+	if tp != "" {
+		stbp.WriteString(tp)
 	}
 
 	stbp.WriteString("struct {\n")
@@ -126,26 +181,26 @@ func definition(stbp *strings.Builder, flds []reflect.StructField, name string,
 
 		nt = sf.Type
 		k = nt.Kind()
+		tp = ""
 		if k == reflect.Slice {
 			nt = nt.Elem()
 			k = nt.Kind()
-			sp = "[]"
-		} else {
-			sp = ""
+			tp = "[]"
 		}
 		if k == reflect.Ptr {
 			nt = nt.Elem()
 			k = nt.Kind()
+			tp += "*"
 		}
 
-		if k == reflect.Struct {
-			// recursively call if type is struct
+		if k == reflect.Struct && hasOnlyExportedFields(nt) {
+			// recursively call if type is struct (except for opaque structs like time.Time)
 			nflds := make([]reflect.StructField, nt.NumField())
 			for i := 0; i < nt.NumField(); i++ {
 				nflds[i] = nt.Field(i)
 			}
 			var nstb strings.Builder
-			stbp.WriteString(definition(&nstb, nflds, "", indentLevel+1, sp))
+			stbp.WriteString(definition(&nstb, nflds, "", indentLevel+1, tp, joinPath(path, sf.Name), ds))
 		} else {
 			stbp.WriteString(sf.Type.String())
 		}
@@ -155,6 +210,18 @@ func definition(stbp *strings.Builder, flds []reflect.StructField, name string,
 			stbp.WriteString(fmt.Sprintf("`%s`", sf.Tag))
 		}
 
+		var comments []string
+		if r, ok := ds.refs[joinPath(path, sf.Name)]; ok {
+			comments = append(comments, "ref: "+r.name)
+		}
+		if m, ok := ds.metas[joinPath(path, sf.Name)]; ok && !m.IsZero() {
+			comments = append(comments, m.comment())
+		}
+		if len(comments) > 0 {
+			stbp.WriteString(" // ")
+			stbp.WriteString(strings.Join(comments, "; "))
+		}
+
 		stbp.WriteString("\n")
 	}
 
@@ -166,6 +233,15 @@ func definition(stbp *strings.Builder, flds []reflect.StructField, name string,
 	return stbp.String()
 }
 
+func hasOnlyExportedFields(t reflect.Type) bool {
+	for i := 0; i < t.NumField(); i++ {
+		if !t.Field(i).IsExported() {
+			return false
+		}
+	}
+	return true
+}
+
 func sortFields(fields []reflect.StructField) []reflect.StructField {
 	sfs := make([]reflect.StructField, len(fields))
 	copy(sfs, fields)
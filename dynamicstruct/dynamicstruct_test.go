@@ -3,10 +3,16 @@
 package dynamicstruct_test
 
 import (
+	"encoding/json"
+	"fmt"
 	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
+
+	"github.com/goldeneggg/structil"
 
 	. "github.com/goldeneggg/structil/dynamicstruct"
 )
@@ -93,25 +99,25 @@ const expectedDefinition = `type DynamicStruct struct {
 	InterfacePtrFieldWithTag *interface {} ` + "`json:\"interface_field_with_tag\"`" + `
 	MapField map[string]float32
 	MapFieldWithTag map[string]float32 ` + "`json:\"map_field_with_tag\"`" + `
-	SliceField []struct {
+	SliceField []*struct {
 		Bool bool
 		Byte uint8
 		Bytes []uint8
 		DynamicTestStruct2 struct {
-			DynamicTestStruct3 struct {
+			DynamicTestStruct3 *struct {
 				Int int
 				String string
 			}
 			String string
 		}
-		DynamicTestStruct2Ptr struct {
-			DynamicTestStruct3 struct {
+		DynamicTestStruct2Ptr *struct {
+			DynamicTestStruct3 *struct {
 				Int int
 				String string
 			}
 			String string
 		}
-		DynamicTestStruct4PtrSlice []struct {
+		DynamicTestStruct4PtrSlice []*struct {
 			String string
 			String2 string
 		}
@@ -131,25 +137,25 @@ const expectedDefinition = `type DynamicStruct struct {
 		Uint uint
 		Uint64 uint64
 	}
-	SliceFieldWithTag []struct {
+	SliceFieldWithTag []*struct {
 		Bool bool
 		Byte uint8
 		Bytes []uint8
 		DynamicTestStruct2 struct {
-			DynamicTestStruct3 struct {
+			DynamicTestStruct3 *struct {
 				Int int
 				String string
 			}
 			String string
 		}
-		DynamicTestStruct2Ptr struct {
-			DynamicTestStruct3 struct {
+		DynamicTestStruct2Ptr *struct {
+			DynamicTestStruct3 *struct {
 				Int int
 				String string
 			}
 			String string
 		}
-		DynamicTestStruct4PtrSlice []struct {
+		DynamicTestStruct4PtrSlice []*struct {
 			String string
 			String2 string
 		}
@@ -176,20 +182,20 @@ const expectedDefinition = `type DynamicStruct struct {
 		Byte uint8
 		Bytes []uint8
 		DynamicTestStruct2 struct {
-			DynamicTestStruct3 struct {
+			DynamicTestStruct3 *struct {
 				Int int
 				String string
 			}
 			String string
 		}
-		DynamicTestStruct2Ptr struct {
-			DynamicTestStruct3 struct {
+		DynamicTestStruct2Ptr *struct {
+			DynamicTestStruct3 *struct {
 				Int int
 				String string
 			}
 			String string
 		}
-		DynamicTestStruct4PtrSlice []struct {
+		DynamicTestStruct4PtrSlice []*struct {
 			String string
 			String2 string
 		}
@@ -214,20 +220,20 @@ const expectedDefinition = `type DynamicStruct struct {
 		Byte uint8
 		Bytes []uint8
 		DynamicTestStruct2 struct {
-			DynamicTestStruct3 struct {
+			DynamicTestStruct3 *struct {
 				Int int
 				String string
 			}
 			String string
 		}
-		DynamicTestStruct2Ptr struct {
-			DynamicTestStruct3 struct {
+		DynamicTestStruct2Ptr *struct {
+			DynamicTestStruct3 *struct {
 				Int int
 				String string
 			}
 			String string
 		}
-		DynamicTestStruct4PtrSlice []struct {
+		DynamicTestStruct4PtrSlice []*struct {
 			String string
 			String2 string
 		}
@@ -247,25 +253,25 @@ const expectedDefinition = `type DynamicStruct struct {
 		Uint uint
 		Uint64 uint64
 	} ` + "`json:\"struct_field_with_tag\"`" + `
-	StructPtrField struct {
+	StructPtrField *struct {
 		Bool bool
 		Byte uint8
 		Bytes []uint8
 		DynamicTestStruct2 struct {
-			DynamicTestStruct3 struct {
+			DynamicTestStruct3 *struct {
 				Int int
 				String string
 			}
 			String string
 		}
-		DynamicTestStruct2Ptr struct {
-			DynamicTestStruct3 struct {
+		DynamicTestStruct2Ptr *struct {
+			DynamicTestStruct3 *struct {
 				Int int
 				String string
 			}
 			String string
 		}
-		DynamicTestStruct4PtrSlice []struct {
+		DynamicTestStruct4PtrSlice []*struct {
 			String string
 			String2 string
 		}
@@ -285,25 +291,25 @@ const expectedDefinition = `type DynamicStruct struct {
 		Uint uint
 		Uint64 uint64
 	}
-	StructPtrFieldWithTag struct {
+	StructPtrFieldWithTag *struct {
 		Bool bool
 		Byte uint8
 		Bytes []uint8
 		DynamicTestStruct2 struct {
-			DynamicTestStruct3 struct {
+			DynamicTestStruct3 *struct {
 				Int int
 				String string
 			}
 			String string
 		}
-		DynamicTestStruct2Ptr struct {
-			DynamicTestStruct3 struct {
+		DynamicTestStruct2Ptr *struct {
+			DynamicTestStruct3 *struct {
 				Int int
 				String string
 			}
 			String string
 		}
-		DynamicTestStruct4PtrSlice []struct {
+		DynamicTestStruct4PtrSlice []*struct {
 			String string
 			String2 string
 		}
@@ -831,6 +837,478 @@ func TestBuilderAddSliceWithNil(t *testing.T) {
 	}
 }
 
+func TestBuilderAddType(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		builder *Builder
+		typ     reflect.Type
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantError bool
+	}{
+		{
+			name: "try to AddType with int64",
+			args: args{builder: newTestBuilder(), typ: reflect.TypeOf(int64(0))},
+		},
+		{
+			name:      "try to AddType with nil",
+			args:      args{builder: newTestBuilder(), typ: nil},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // See: https://gist.github.com/posener/92a55c4cd441fc5e5e85f27bca008721
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ds, err := tt.args.builder.AddType("TypeField", tt.args.typ).Build()
+			if err != nil {
+				if !tt.wantError {
+					t.Errorf("unexpected error occurred: args: %+v, %v", tt.args, err)
+				}
+				return
+			} else if tt.wantError {
+				t.Errorf("expect to occur error but does not: args: %+v", tt.args)
+				return
+			}
+
+			if f, _ := ds.FieldByName("TypeField"); f.Type != tt.args.typ {
+				t.Errorf("unexpected type of TypeField. got: %v, want: %v", f.Type, tt.args.typ)
+			}
+		})
+	}
+}
+
+func TestBuilderRename(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		builder *Builder
+		oldName string
+		newName string
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantFields []string
+		wantError  bool
+	}{
+		{
+			name: "rename keeps the position and the tag",
+			args: args{
+				builder: NewBuilder().AddString("A").AddIntWithTag("B", intFieldTag).AddBool("C"),
+				oldName: "B",
+				newName: "Renamed",
+			},
+			wantFields: []string{"A", "Renamed", "C"},
+		},
+		{
+			name: "rename non-existent field does nothing",
+			args: args{
+				builder: NewBuilder().AddString("A"),
+				oldName: "X",
+				newName: "Y",
+			},
+			wantFields: []string{"A"},
+		},
+		{
+			name: "rename to existing field",
+			args: args{
+				builder: NewBuilder().AddString("A").AddString("B"),
+				oldName: "A",
+				newName: "B",
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // See: https://gist.github.com/posener/92a55c4cd441fc5e5e85f27bca008721
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ds, err := tt.args.builder.Rename(tt.args.oldName, tt.args.newName).Build()
+			if err != nil {
+				if !tt.wantError {
+					t.Fatalf("unexpected error occurred: args: %+v, %v", tt.args, err)
+				}
+				return
+			} else if tt.wantError {
+				t.Fatalf("expect to occur error but does not: args: %+v", tt.args)
+			}
+
+			if d := cmp.Diff(fieldNames(ds), tt.wantFields); d != "" {
+				t.Fatalf("unexpected mismatch fields: (-got +want)\n%s", d)
+			}
+
+			if tt.args.newName == "Renamed" {
+				if f, _ := ds.FieldByName("Renamed"); f.Tag != intFieldTag {
+					t.Errorf("unexpected tag of renamed field. got: %s, want: %s", f.Tag, intFieldTag)
+				}
+			}
+		})
+	}
+}
+
+func TestBuilderFieldOrder(t *testing.T) {
+	t.Parallel()
+
+	ds, err := NewBuilder().
+		AddString("Z").
+		AddInt("A").
+		AddBool("M").
+		AddFloat64("B").
+		Remove("M").
+		AddBool("M").
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	if d := cmp.Diff(fieldNames(ds), []string{"Z", "A", "B", "M"}); d != "" {
+		t.Fatalf("unexpected mismatch field order: (-got +want)\n%s", d)
+	}
+}
+
+func TestNewBuilderFromType(t *testing.T) {
+	t.Parallel()
+
+	type withUnexported struct {
+		ID       int    `json:"id"`
+		Name     string `json:"name"`
+		password string
+	}
+
+	type args struct {
+		builder *Builder
+	}
+	tests := []struct {
+		name           string
+		args           args
+		wantStructName string
+		wantFields     []string
+		wantError      bool
+	}{
+		{
+			name:           "from struct",
+			args:           args{builder: NewBuilderFrom(DynamicTestStruct4{})},
+			wantStructName: "DynamicTestStruct4",
+			wantFields:     []string{"String", "String2"},
+		},
+		{
+			name:           "from struct pointer",
+			args:           args{builder: NewBuilderFrom(&DynamicTestStruct4{})},
+			wantStructName: "DynamicTestStruct4",
+			wantFields:     []string{"String", "String2"},
+		},
+		{
+			name:           "from type with embedded fields",
+			args:           args{builder: NewBuilderFromType(reflect.TypeOf(DynamicTestStruct2{}))},
+			wantStructName: "DynamicTestStruct2",
+			wantFields:     []string{"String", "Int"},
+		},
+		{
+			name:           "unexported fields are skipped",
+			args:           args{builder: NewBuilderFrom(withUnexported{})},
+			wantStructName: "withUnexported",
+			wantFields:     []string{"ID", "Name"},
+		},
+		{
+			name:           "from unnamed struct",
+			args:           args{builder: NewBuilderFrom(struct{ A string }{})},
+			wantStructName: "DynamicStruct",
+			wantFields:     []string{"A"},
+		},
+		{
+			name:      "from non-struct",
+			args:      args{builder: NewBuilderFrom(SampleInt)},
+			wantError: true,
+		},
+		{
+			name:      "from nil",
+			args:      args{builder: NewBuilderFrom(nil)},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt // See: https://gist.github.com/posener/92a55c4cd441fc5e5e85f27bca008721
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ds, err := tt.args.builder.Build()
+			if err != nil {
+				if !tt.wantError {
+					t.Fatalf("unexpected error occurred: %v", err)
+				}
+				return
+			} else if tt.wantError {
+				t.Fatalf("expect to occur error but does not: args: %+v", tt.args)
+			}
+
+			if ds.Name() != tt.wantStructName {
+				t.Errorf("unexpected struct name. got: %s, want: %s", ds.Name(), tt.wantStructName)
+			}
+
+			if d := cmp.Diff(fieldNames(ds), tt.wantFields); d != "" {
+				t.Fatalf("unexpected mismatch fields: (-got +want)\n%s", d)
+			}
+		})
+	}
+}
+
+func TestNewBuilderFromTypeEmbeddedJSON(t *testing.T) {
+	t.Parallel()
+
+	type Base struct {
+		ID        int    `json:"id"`
+		CreatedBy string `json:"created_by"`
+	}
+	type audit struct {
+		UpdatedBy string `json:"updated_by"`
+	}
+	type Internal struct {
+		Base
+		*audit
+		Owner    Base   `json:"owner"`
+		Name     string `json:"name"`
+		password string
+	}
+
+	ds, err := NewBuilderFrom(Internal{}).Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+	if d := cmp.Diff(fieldNames(ds), []string{"ID", "CreatedBy", "UpdatedBy", "Owner", "Name"}); d != "" {
+		t.Fatalf("unexpected mismatch fields: (-got +want)\n%s", d)
+	}
+
+	// the JSON of the built struct has the same shape as the original struct
+	org := Internal{
+		Base:     Base{ID: 1, CreatedBy: "a"},
+		audit:    &audit{UpdatedBy: "b"},
+		Owner:    Base{ID: 2, CreatedBy: "c"},
+		Name:     "n",
+		password: "p",
+	}
+	want, err := json.Marshal(org)
+	if err != nil {
+		t.Fatalf("unexpected error caused by json.Marshal: %v", err)
+	}
+	intf := ds.NewInterface()
+	if err := json.Unmarshal(want, intf); err != nil {
+		t.Fatalf("unexpected error caused by json.Unmarshal: %v", err)
+	}
+	got, err := json.Marshal(intf)
+	if err != nil {
+		t.Fatalf("unexpected error caused by json.Marshal: %v", err)
+	}
+	if d := cmp.Diff(string(got), string(want)); d != "" {
+		t.Errorf("mismatch JSON: (-got +want)\n%s", d)
+	}
+}
+
+func TestDynamicStructToBuilder(t *testing.T) {
+	t.Parallel()
+
+	ds, err := newTestBuilderWithStructName("Origin").Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	b := ds.ToBuilder()
+	if b.GetStructName() != "Origin" {
+		t.Errorf("unexpected struct name. got: %s, want: Origin", b.GetStructName())
+	}
+	if b.NumField() != ds.NumField() {
+		t.Errorf("unexpected numfield. got: %d, want: %d", b.NumField(), ds.NumField())
+	}
+
+	rds, err := b.Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by rebuild: %v", err)
+	}
+	if rds.Type() != ds.Type() {
+		t.Errorf("rebuilt type is not identical. got: %v, want: %v", rds.Type(), ds.Type())
+	}
+
+	// modifying the Builder does not affect the original DynamicStruct
+	mds, err := b.
+		Remove("IntField").
+		Rename("StringFieldWithTag", "Renamed").
+		SetTag("Renamed", `json:"renamed"`).
+		AddString("Added").
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by modified Build: %v", err)
+	}
+	if mds.NumField() != ds.NumField() {
+		t.Errorf("unexpected numfield of modified. got: %d, want: %d", mds.NumField(), ds.NumField())
+	}
+	if _, ok := ds.FieldByName("IntField"); !ok {
+		t.Errorf("original DynamicStruct is modified")
+	}
+	if f, ok := mds.FieldByName("Renamed"); !ok || f.Tag != `json:"renamed"` {
+		t.Errorf("unexpected Renamed field. got: %+v", f)
+	}
+}
+
+func TestBuilderClone(t *testing.T) {
+	t.Parallel()
+
+	base := NewBuilder().
+		SetStructName("Base").
+		AddStringWithTag("ID", `json:"id"`).
+		SetMeta("ID", FieldMeta{Description: "identifier"})
+
+	c := base.Clone().
+		SetStructName("Tenant").
+		SetTag("ID", `json:"tenant_id"`).
+		SetMeta("ID", FieldMeta{Description: "tenant identifier"}).
+		AddInt("Quota")
+
+	bds, err := base.Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by base Build: %v", err)
+	}
+	cds, err := c.Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by clone Build: %v", err)
+	}
+
+	if d := cmp.Diff(fieldNames(bds), []string{"ID"}); d != "" {
+		t.Errorf("base is modified by clone: (-got +want)\n%s", d)
+	}
+	if f, _ := bds.FieldByName("ID"); f.Tag != `json:"id"` {
+		t.Errorf("unexpected base tag: %s", f.Tag)
+	}
+	if m, _ := bds.Meta("ID"); m.Description != "identifier" {
+		t.Errorf("unexpected base meta: %+v", m)
+	}
+
+	if d := cmp.Diff(fieldNames(cds), []string{"ID", "Quota"}); d != "" {
+		t.Errorf("mismatch clone fields: (-got +want)\n%s", d)
+	}
+	if cds.Name() != "Tenant" {
+		t.Errorf("unexpected clone name: %s", cds.Name())
+	}
+	if f, _ := cds.FieldByName("ID"); f.Tag != `json:"tenant_id"` {
+		t.Errorf("unexpected clone tag: %s", f.Tag)
+	}
+
+	// the error is also cloned
+	if _, err := NewBuilder().AddString("").Clone().Build(); err == nil {
+		t.Errorf("expected error is not occurred")
+	}
+}
+
+func TestBuilderConcurrent(t *testing.T) {
+	t.Parallel()
+
+	base := newTestBuilder()
+	numField := base.NumField()
+
+	const n = 20
+	errs := make(chan error, n*2)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+
+			ds, err := base.Clone().AddInt(fmt.Sprintf("Tenant%d", i)).Build()
+			if err == nil && ds.NumField() != numField+1 {
+				err = fmt.Errorf("unexpected numfield %d", ds.NumField())
+			}
+			errs <- err
+		}(i)
+		go func(i int) {
+			defer wg.Done()
+
+			// readers and writers of the shared Builder do not race
+			base.SetTag("IntField", fmt.Sprintf(`json:"int_%d"`, i))
+			_ = base.GetTag("IntField")
+			_, err := base.Build()
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+}
+
+func TestBuilderConcurrentNested(t *testing.T) {
+	t.Parallel()
+
+	nds, err := NewBuilder().
+		AddStringWithTag("Key", `json:"key"`).
+		AddRefWithTag("Parent", NewRef("Node"), `json:"parent"`).
+		SetMeta("Key", FieldMeta{Description: "the key"}).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+	base := NewBuilder().AddString("Name")
+
+	const n = 20
+	errs := make(chan error, n*2)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+
+			if i%2 == 0 {
+				base.AddDynamicStructWithTag("Obj", nds, false, `json:"obj"`)
+			} else {
+				base.AddDynamicStructSliceWithTag("Obj", nds, `json:"obj"`)
+			}
+			errs <- nil
+		}(i)
+		go func() {
+			defer wg.Done()
+
+			// the nested metadata and Refs are added with the field atomically
+			ds, err := base.Clone().Build()
+			if err == nil {
+				if _, ok := ds.FieldByName("Obj"); ok {
+					if _, ok := ds.Meta("Obj.Key"); !ok {
+						err = fmt.Errorf("Obj.Key has no metadata")
+					} else if _, ok := ds.Ref("Obj.Parent"); !ok {
+						err = fmt.Errorf("Obj.Parent has no Ref")
+					}
+				}
+			}
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+}
+
+func fieldNames(ds *DynamicStruct) []string {
+	names := make([]string, ds.NumField())
+	for i := 0; i < ds.NumField(); i++ {
+		names[i] = ds.Field(i).Name
+	}
+	return names
+}
+
 type buildArgs struct {
 	builder *Builder
 }
@@ -1121,3 +1599,62 @@ func testBuilderBuildAddDynamicStruct(t *testing.T, ds *DynamicStruct, tt buildT
 
 	return true
 }
+
+func TestDynamicStructNewSliceAndMap(t *testing.T) {
+	t.Parallel()
+
+	ds, err := NewBuilder().
+		AddStringWithTag("Key", `json:"key" yaml:"key"`).
+		AddIntWithTag("Value", `json:"value" yaml:"value"`).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	s := ds.NewSlice(0, 2)
+	if err := json.Unmarshal([]byte(`[{"key":"a","value":1},{"key":"b","value":2}]`), s); err != nil {
+		t.Fatalf("unexpected error is returned from json.Unmarshal: %v", err)
+	}
+	rv := reflect.ValueOf(s).Elem()
+	if rv.Type() != reflect.SliceOf(ds.Type()) || rv.Len() != 2 {
+		t.Fatalf("unexpected NewSlice result. got: %#v", s)
+	}
+	if got := rv.Index(1).FieldByName("Key").String(); got != "b" {
+		t.Errorf("unexpected Key of 2nd element. got: %s, want: b", got)
+	}
+
+	sp := ds.NewSliceOfPtr(0, 0)
+	if err := yaml.Unmarshal([]byte("- key: a\n  value: 1\n"), sp); err != nil {
+		t.Fatalf("unexpected error is returned from yaml.Unmarshal: %v", err)
+	}
+	rv = reflect.ValueOf(sp).Elem()
+	if rv.Type() != reflect.SliceOf(reflect.PtrTo(ds.Type())) || rv.Len() != 1 {
+		t.Fatalf("unexpected NewSliceOfPtr result. got: %#v", sp)
+	}
+	g, err := structil.NewGetter(rv.Index(0).Interface())
+	if err != nil {
+		t.Fatalf("unexpected error is returned from NewGetter: %v", err)
+	}
+	if got, _ := g.Int("Value"); got != 1 {
+		t.Errorf("unexpected Value of 1st element. got: %d, want: 1", got)
+	}
+
+	m, err := ds.NewMap(reflect.TypeOf(""))
+	if err != nil {
+		t.Fatalf("unexpected error is returned from NewMap: %v", err)
+	}
+	if err := json.Unmarshal([]byte(`{"x":{"key":"a","value":1}}`), m); err != nil {
+		t.Fatalf("unexpected error is returned from json.Unmarshal: %v", err)
+	}
+	mv := reflect.ValueOf(m).Elem()
+	if mv.Len() != 1 || mv.MapIndex(reflect.ValueOf("x")).FieldByName("Key").String() != "a" {
+		t.Errorf("unexpected NewMap result. got: %#v", m)
+	}
+
+	if _, err := ds.NewMap(reflect.TypeOf([]string{})); err == nil {
+		t.Errorf("error is expected but it does not occur from NewMap with non comparable key")
+	}
+	if _, err := ds.NewMap(nil); err == nil {
+		t.Errorf("error is expected but it does not occur from NewMap with nil key")
+	}
+}
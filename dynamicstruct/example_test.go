@@ -54,7 +54,7 @@ func Example() {
 	// 	SliceField []int
 	// 	SomeObjectField *interface {} `json:"some_object_field"`
 	// 	StringField string
-	// 	StructPtrField struct {
+	// 	StructPtrField *struct {
 	// 		Key string
 	// 		Value interface {}
 	// 	}
@@ -131,7 +131,7 @@ func Example_unmarshalJSON() {
 	// 	IntField int `json:"int_field"`
 	// 	SliceField []string `json:"slice_string_field"`
 	// 	StringField string `json:"string_field"`
-	// 	StructPtrField struct {
+	// 	StructPtrField *struct {
 	// 		Key string `json:"key"`
 	// 		Value interface {} `json:"value"`
 	// 	} `json:"struct_ptr_field"`
@@ -142,3 +142,132 @@ func Example_unmarshalJSON() {
 	// 'StructPtrField'={Key:hogekey Value:hogevalue}
 	// 'SliceField'=[a b]
 }
+
+func ExampleDynamicStruct_GoSource() {
+	type Hoge struct {
+		Key   string
+		Value interface{}
+	}
+
+	ds, err := NewBuilder().
+		SetStructName("MyStruct").
+		AddStringWithTag("StringField", `json:"string_field"`).
+		AddStructPtr("HogeField", &Hoge{}).
+		AddSlice("SliceField", SampleInt).
+		Build()
+	if err != nil {
+		panic(err)
+	}
+
+	// GoSource returns a formatted Go source file
+	// Nested structs are declared as named types
+	src, err := ds.GoSource(GoSourceOptions{PackageName: "model", JSONTag: true})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Print(string(src))
+
+	// Output:
+	// // Code generated by structil. DO NOT EDIT.
+	//
+	// package model
+	//
+	// // MyStruct is a struct generated from DynamicStruct.
+	// type MyStruct struct {
+	// 	HogeField   *HogeField `json:"hoge_field"`
+	// 	SliceField  []int      `json:"slice_field"`
+	// 	StringField string     `json:"string_field"`
+	// }
+	//
+	// // HogeField is a struct generated from DynamicStruct.
+	// type HogeField struct {
+	// 	Key   string      `json:"key"`
+	// 	Value interface{} `json:"value"`
+	// }
+}
+
+func ExampleDynamicStruct_ToBuilder() {
+	type User struct {
+		ID       int    `json:"id"`
+		Name     string `json:"name"`
+		Email    string `json:"email"`
+		Password string `json:"password"`
+	}
+
+	// NewBuilderFrom seeds a Builder with fields of an existing struct
+	ds, err := NewBuilderFrom(User{}).
+		SetStructName("PublicUser").
+		Remove("Password").
+		Rename("Email", "Contact").
+		SetTag("Contact", `json:"contact"`).
+		Build()
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(ds.Definition())
+
+	// ToBuilder returns a new Builder from a DynamicStruct
+	ads, err := ds.ToBuilder().
+		SetStructName("AdminUser").
+		AddBoolWithTag("IsAdmin", `json:"is_admin"`).
+		Build()
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(ads.Definition())
+
+	// Output:
+	// type PublicUser struct {
+	// 	Contact string `json:"contact"`
+	// 	ID int `json:"id"`
+	// 	Name string `json:"name"`
+	// }
+	// type AdminUser struct {
+	// 	Contact string `json:"contact"`
+	// 	ID int `json:"id"`
+	// 	IsAdmin bool `json:"is_admin"`
+	// 	Name string `json:"name"`
+	// }
+}
+
+func ExampleDynamicStruct_New() {
+	ads, err := NewBuilder().
+		AddStringWithTag("City", `json:"city"`).
+		BuildNonPtr()
+	if err != nil {
+		panic(err)
+	}
+
+	ds, err := NewBuilder().
+		AddStringWithTag("Name", `json:"name"`).
+		AddIntWithTag("Age", `json:"age"`).
+		AddDynamicStructPtrWithTag("Address", ads, `json:"address"`).
+		Build()
+	if err != nil {
+		panic(err)
+	}
+
+	// New returns an Instance that sets and gets fields with type checks
+	ins := ds.New()
+	if err := ins.Set("Name", "Alice"); err != nil {
+		panic(err)
+	}
+	if err := ins.Set("Age", "twenty"); err != nil {
+		fmt.Println(err)
+	}
+
+	// SetPath allocates nil pointers on the path
+	if err := ins.SetPath("Address.City", "Tokyo"); err != nil {
+		panic(err)
+	}
+
+	b, err := json.Marshal(ins.Interface())
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(b))
+
+	// Output:
+	// field Age: value of type string can not be set to int
+	// {"name":"Alice","age":0,"address":{"city":"Tokyo"}}
+}
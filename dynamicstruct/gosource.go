@@ -0,0 +1,378 @@
+package dynamicstruct
+
+import (
+	"fmt"
+	"go/format"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+const defaultPackageName = "main"
+
+// GoSourceOptions is the options for DynamicStruct.GoSource.
+type GoSourceOptions struct {
+	// PackageName is the package name of the generated source.
+	// Default package name is "main"
+	PackageName string
+
+	// JSONTag adds a json tag to the fields that do not have it.
+	JSONTag bool
+
+	// YAMLTag adds a yaml tag to the fields that do not have it.
+	YAMLTag bool
+
+	// Comments is the field comments keyed by the field path from the top level struct.
+	// (e.g. "ObjField" or "ObjField.Id")
+	// If a field has no comment, the description and the deprecation in its metadata are used.
+	Comments map[string]string
+}
+
+// GoSource returns a formatted Go source file that declares the struct type of this.
+// Nested anonymous structs are declared as named types.
+// The name of a nested type is the name of the field that has it.
+// Fields referring to resolved Refs are declared as the pointers (or the slices of pointers) to the referred types.
+func (ds *DynamicStruct) GoSource(opts GoSourceOptions) ([]byte, error) {
+	g := newGoSourceGen(opts)
+	if err := g.generate(ds); err != nil {
+		return nil, err
+	}
+
+	src, err := format.Source(g.bytes())
+	if err != nil {
+		return nil, fmt.Errorf("fail to format generated source: %w", err)
+	}
+
+	return src, nil
+}
+
+type goTypeDecl struct {
+	name string
+	path string
+	typ  reflect.Type
+	ds   *DynamicStruct // the DynamicStruct that path is relative to
+}
+
+type goSourceGen struct {
+	opts    GoSourceOptions
+	cur     *DynamicStruct // the DynamicStruct of the declaring type
+	namer   *typeNamer
+	imports map[string]string // import path -> package name
+	queue   []goTypeDecl
+	stb     strings.Builder
+}
+
+func newGoSourceGen(opts GoSourceOptions) *goSourceGen {
+	if opts.PackageName == "" {
+		opts.PackageName = defaultPackageName
+	}
+
+	return &goSourceGen{
+		opts:    opts,
+		namer:   newTypeNamer(),
+		imports: make(map[string]string),
+	}
+}
+
+func (g *goSourceGen) generate(ds *DynamicStruct) error {
+	g.namer.reserve(ds.name, ds.rt)
+	g.queue = append(g.queue, goTypeDecl{name: ds.name, typ: ds.rt, ds: ds})
+
+	var body strings.Builder
+	// g.queue grows while declaring types
+	for i := 0; i < len(g.queue); i++ {
+		if err := g.declare(&body, g.queue[i]); err != nil {
+			return err
+		}
+	}
+
+	g.stb.WriteString("// Code generated by structil. DO NOT EDIT.\n\n")
+	g.stb.WriteString("package " + g.opts.PackageName + "\n\n")
+
+	if len(g.imports) > 0 {
+		paths := make([]string, 0, len(g.imports))
+		for p := range g.imports {
+			paths = append(paths, p)
+		}
+		sort.Strings(paths)
+
+		g.stb.WriteString("import (\n")
+		for _, p := range paths {
+			g.stb.WriteString(fmt.Sprintf("\t%q\n", p))
+		}
+		g.stb.WriteString(")\n\n")
+	}
+
+	g.stb.WriteString(body.String())
+
+	return nil
+}
+
+func (g *goSourceGen) bytes() []byte {
+	return []byte(g.stb.String())
+}
+
+func (g *goSourceGen) declare(stbp *strings.Builder, decl goTypeDecl) error {
+	stbp.WriteString(fmt.Sprintf("// %s is a struct generated from DynamicStruct.\n", decl.name))
+	stbp.WriteString("type " + decl.name + " struct {\n")
+	g.cur = decl.ds
+
+	for _, sf := range sortFields(structFields(decl.typ)) {
+		path := sf.Name
+		if decl.path != "" {
+			path = decl.path + "." + sf.Name
+		}
+
+		if c := g.comment(path); c != "" {
+			for _, line := range strings.Split(c, "\n") {
+				stbp.WriteString("\t// " + line + "\n")
+			}
+		}
+
+		var te string
+		var err error
+		if r, ok := g.cur.refs[path]; ok && r.ds != nil {
+			te = g.refExpr(r, sf.Type)
+		} else {
+			te, err = g.typeExpr(sf.Type, sf.Name, path)
+		}
+		if err != nil {
+			return fmt.Errorf("field %s: %w", path, err)
+		}
+
+		stbp.WriteString("\t")
+		// keep embedded field only when the type expression is the field name itself
+		if !sf.Anonymous || strings.TrimPrefix(te, "*") != sf.Name {
+			stbp.WriteString(sf.Name + " ")
+		}
+		stbp.WriteString(te)
+
+		if tag := g.tag(sf); tag != "" {
+			stbp.WriteString(" `" + tag + "`")
+		}
+		stbp.WriteString("\n")
+	}
+
+	stbp.WriteString("}\n\n")
+
+	return nil
+}
+
+func (g *goSourceGen) comment(path string) string {
+	if c, ok := g.opts.Comments[path]; ok && c != "" {
+		return c
+	}
+
+	m := g.cur.metas[path]
+	c := m.Description
+	if m.Deprecated {
+		if c != "" {
+			c += "\n\n"
+		}
+		c += "Deprecated: this field is deprecated."
+	}
+
+	return c
+}
+
+func (g *goSourceGen) tag(sf reflect.StructField) string {
+	tag := string(sf.Tag)
+
+	add := func(key string) {
+		if _, ok := sf.Tag.Lookup(key); ok {
+			return
+		}
+		if tag != "" {
+			tag += " "
+		}
+		tag += NewTag().Set(key, tagNameOf(sf)).String()
+	}
+
+	if g.opts.JSONTag {
+		add("json")
+	}
+	if g.opts.YAMLTag {
+		add("yaml")
+	}
+
+	return tag
+}
+
+// tagNameOf returns the name in the existing json or yaml tag of sf.
+// If sf has neither of them, this returns the snake cased field name.
+func tagNameOf(sf reflect.StructField) string {
+	for _, key := range []string{"json", "yaml"} {
+		if v, ok := sf.Tag.Lookup(key); ok {
+			if n := strings.Split(v, ",")[0]; n != "" && n != "-" {
+				return n
+			}
+		}
+	}
+
+	return strcase.ToSnake(sf.Name)
+}
+
+func (g *goSourceGen) typeExpr(t reflect.Type, hint string, path string) (string, error) {
+	if t.Name() != "" {
+		if t.PkgPath() == "" {
+			// predeclared types (e.g. "int", "error")
+			return t.Name(), nil
+		}
+
+		if !isExportedName(t.Name()) {
+			return "", fmt.Errorf("unexported type %s can not be referred", t)
+		}
+
+		s := t.String()
+		g.imports[t.PkgPath()] = s[:strings.Index(s, ".")]
+		return s, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		e, err := g.typeExpr(t.Elem(), hint, path)
+		return "*" + e, err
+	case reflect.Slice:
+		e, err := g.typeExpr(t.Elem(), hint, path)
+		return "[]" + e, err
+	case reflect.Array:
+		e, err := g.typeExpr(t.Elem(), hint, path)
+		return fmt.Sprintf("[%d]%s", t.Len(), e), err
+	case reflect.Map:
+		k, err := g.typeExpr(t.Key(), hint+"Key", path)
+		if err != nil {
+			return "", err
+		}
+		e, err := g.typeExpr(t.Elem(), hint, path)
+		return "map[" + k + "]" + e, err
+	case reflect.Chan:
+		e, err := g.typeExpr(t.Elem(), hint, path)
+		switch t.ChanDir() {
+		case reflect.RecvDir:
+			return "<-chan " + e, err
+		case reflect.SendDir:
+			return "chan<- " + e, err
+		default:
+			return "chan " + e, err
+		}
+	case reflect.Func:
+		return g.funcExpr(t, hint, path)
+	case reflect.Interface:
+		if t.NumMethod() == 0 {
+			return "interface{}", nil
+		}
+		return "", fmt.Errorf("unsupported non-empty unnamed interface %s", t)
+	case reflect.Struct:
+		name, isNew := g.namer.nameOf(t, hint)
+		if isNew {
+			g.queue = append(g.queue, goTypeDecl{name: name, path: path, typ: t, ds: g.cur})
+		}
+		return name, nil
+	}
+
+	return "", fmt.Errorf("unsupported type %s", t)
+}
+
+// refExpr returns the pointer type expression to the type referred by r.
+func (g *goSourceGen) refExpr(r *Ref, t reflect.Type) string {
+	name, isNew := g.namer.nameOf(r.ds.rt, r.name)
+	if isNew {
+		g.queue = append(g.queue, goTypeDecl{name: name, typ: r.ds.rt, ds: r.ds})
+	}
+
+	if t.Kind() == reflect.Slice {
+		return "[]*" + name
+	}
+	return "*" + name
+}
+
+func (g *goSourceGen) funcExpr(t reflect.Type, hint string, path string) (string, error) {
+	ins := make([]string, t.NumIn())
+	for i := 0; i < t.NumIn(); i++ {
+		in, err := g.typeExpr(t.In(i), hint, path)
+		if err != nil {
+			return "", err
+		}
+		if t.IsVariadic() && i == t.NumIn()-1 {
+			in = "..." + strings.TrimPrefix(in, "[]")
+		}
+		ins[i] = in
+	}
+
+	outs := make([]string, t.NumOut())
+	for i := 0; i < t.NumOut(); i++ {
+		out, err := g.typeExpr(t.Out(i), hint, path)
+		if err != nil {
+			return "", err
+		}
+		outs[i] = out
+	}
+
+	s := "func(" + strings.Join(ins, ", ") + ")"
+	switch len(outs) {
+	case 0:
+	case 1:
+		s += " " + outs[0]
+	default:
+		s += " (" + strings.Join(outs, ", ") + ")"
+	}
+
+	return s, nil
+}
+
+// typeNamer assigns the unique type names to unnamed struct types.
+type typeNamer struct {
+	names  map[string]reflect.Type
+	byType map[reflect.Type]string
+}
+
+func newTypeNamer() *typeNamer {
+	return &typeNamer{
+		names:  make(map[string]reflect.Type),
+		byType: make(map[reflect.Type]string),
+	}
+}
+
+func (tn *typeNamer) reserve(name string, t reflect.Type) {
+	tn.names[name] = t
+	tn.byType[t] = name
+}
+
+// nameOf returns the type name of t and whether the name is newly assigned.
+// Identical struct types share the same name.
+func (tn *typeNamer) nameOf(t reflect.Type, hint string) (string, bool) {
+	if name, ok := tn.byType[t]; ok {
+		return name, false
+	}
+
+	name := strcase.ToCamel(hint)
+	if name == "" {
+		name = defaultStructName
+	}
+	if _, ok := tn.names[name]; ok {
+		base := name
+		for i := 2; ; i++ {
+			name = fmt.Sprintf("%s%d", base, i)
+			if _, ok := tn.names[name]; !ok {
+				break
+			}
+		}
+	}
+
+	tn.reserve(name, t)
+	return name, true
+}
+
+func isExportedName(name string) bool {
+	return name != "" && strings.ToUpper(name[:1]) == name[:1]
+}
+
+func structFields(t reflect.Type) []reflect.StructField {
+	flds := make([]reflect.StructField, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		flds[i] = t.Field(i)
+	}
+	return flds
+}
@@ -0,0 +1,233 @@
+package dynamicstruct_test
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	. "github.com/goldeneggg/structil/dynamicstruct"
+)
+
+func TestGoSource(t *testing.T) {
+	t.Parallel()
+
+	nested, err := NewBuilder().
+		AddStringWithTag("Key", `json:"key"`).
+		AddFloat64WithTag("Value", `json:"value"`).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by nested Build: %v", err)
+	}
+
+	type args struct {
+		builder *Builder
+		opts    GoSourceOptions
+	}
+	tests := []struct {
+		name      string
+		args      args
+		wantSrc   string
+		wantError bool
+	}{
+		{
+			name: "primitive fields",
+			args: args{
+				builder: NewBuilder().
+					AddString("StringField").
+					AddIntWithTag("IntField", `json:"int_field"`).
+					AddBool("BoolField"),
+			},
+			wantSrc: `// Code generated by structil. DO NOT EDIT.
+
+package main
+
+// DynamicStruct is a struct generated from DynamicStruct.
+type DynamicStruct struct {
+	BoolField   bool
+	IntField    int ` + "`json:\"int_field\"`" + `
+	StringField string
+}
+`,
+		},
+		{
+			name: "nested DynamicStructs are declared as named types",
+			args: args{
+				builder: NewBuilder().
+					SetStructName("Root").
+					AddDynamicStructWithTag("Item", nested, false, `json:"item"`).
+					AddDynamicStructPtrWithTag("ItemPtr", nested, `json:"item_ptr"`).
+					AddDynamicStructSliceWithTag("Items", nested, `json:"items"`),
+				opts: GoSourceOptions{PackageName: "model"},
+			},
+			wantSrc: `// Code generated by structil. DO NOT EDIT.
+
+package model
+
+// Root is a struct generated from DynamicStruct.
+type Root struct {
+	Item    Item    ` + "`json:\"item\"`" + `
+	ItemPtr *Item   ` + "`json:\"item_ptr\"`" + `
+	Items   []*Item ` + "`json:\"items\"`" + `
+}
+
+// Item is a struct generated from DynamicStruct.
+type Item struct {
+	Key   string  ` + "`json:\"key\"`" + `
+	Value float64 ` + "`json:\"value\"`" + `
+}
+`,
+		},
+		{
+			name: "imports, tags and comments",
+			args: args{
+				builder: NewBuilder().
+					AddSliceWithTag("Times", time.Time{}, `yaml:"times"`).
+					AddMap("Durations", SampleString, time.Duration(0)).
+					AddDynamicStruct("Item", nested, false),
+				opts: GoSourceOptions{
+					PackageName: "model",
+					JSONTag:     true,
+					YAMLTag:     true,
+					Comments: map[string]string{
+						"Times":    "Times is the list of times.",
+						"Item.Key": "Key is the key of item.",
+					},
+				},
+			},
+			wantSrc: `// Code generated by structil. DO NOT EDIT.
+
+package model
+
+import (
+	"time"
+)
+
+// DynamicStruct is a struct generated from DynamicStruct.
+type DynamicStruct struct {
+	Durations map[string]time.Duration ` + "`json:\"durations\" yaml:\"durations\"`" + `
+	Item      Item                     ` + "`json:\"item\" yaml:\"item\"`" + `
+	// Times is the list of times.
+	Times []time.Time ` + "`yaml:\"times\" json:\"times\"`" + `
+}
+
+// Item is a struct generated from DynamicStruct.
+type Item struct {
+	// Key is the key of item.
+	Key   string  ` + "`json:\"key\" yaml:\"key\"`" + `
+	Value float64 ` + "`json:\"value\" yaml:\"value\"`" + `
+}
+`,
+		},
+		{
+			name: "func and chan fields",
+			args: args{
+				builder: NewBuilder().
+					AddFunc("FuncField", []interface{}{SampleInt, SampleString}, []interface{}{SampleBool}).
+					AddChanRecv("ChanRecvField", SampleInt).
+					AddChanSend("ChanSendField", SampleInt),
+			},
+			wantSrc: `// Code generated by structil. DO NOT EDIT.
+
+package main
+
+// DynamicStruct is a struct generated from DynamicStruct.
+type DynamicStruct struct {
+	ChanRecvField <-chan int
+	ChanSendField chan<- int
+	FuncField     func(int, string) bool
+}
+`,
+		},
+		{
+			name: "unexported type can not be referred",
+			args: args{
+				builder: NewBuilder().
+					AddFunc("FuncField", nil, []interface{}{ErrSample}),
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ds, err := tt.args.builder.Build()
+			if err != nil {
+				t.Fatalf("unexpected error caused by Build: %v", err)
+			}
+
+			src, err := ds.GoSource(tt.args.opts)
+			if err != nil {
+				if !tt.wantError {
+					t.Fatalf("unexpected error is returned from GoSource: %v", err)
+				}
+				return
+			} else if tt.wantError {
+				t.Fatalf("error is expected but it does not occur from GoSource. src:\n%s", src)
+			}
+
+			if d := cmp.Diff(string(src), tt.wantSrc); d != "" {
+				t.Fatalf("mismatch GoSource: (-got +want)\n%s", d)
+			}
+
+			if _, err := parser.ParseFile(token.NewFileSet(), "", src, parser.AllErrors); err != nil {
+				t.Fatalf("generated source is invalid: %v", err)
+			}
+		})
+	}
+}
+
+func TestGoSourceNameConflict(t *testing.T) {
+	t.Parallel()
+
+	a, _ := NewBuilder().AddString("A").Build()
+	b, _ := NewBuilder().AddInt("B").Build()
+	inner, _ := NewBuilder().AddDynamicStruct("Item", b, false).Build()
+
+	ds, err := NewBuilder().
+		AddDynamicStruct("Item", a, false).
+		AddDynamicStruct("Inner", inner, false).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	src, err := ds.GoSource(GoSourceOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error is returned from GoSource: %v", err)
+	}
+
+	want := `// Code generated by structil. DO NOT EDIT.
+
+package main
+
+// DynamicStruct is a struct generated from DynamicStruct.
+type DynamicStruct struct {
+	Inner Inner
+	Item  Item
+}
+
+// Inner is a struct generated from DynamicStruct.
+type Inner struct {
+	Item Item2
+}
+
+// Item is a struct generated from DynamicStruct.
+type Item struct {
+	A string
+}
+
+// Item2 is a struct generated from DynamicStruct.
+type Item2 struct {
+	B int
+}
+`
+	if d := cmp.Diff(string(src), want); d != "" {
+		t.Fatalf("mismatch GoSource: (-got +want)\n%s", d)
+	}
+}
@@ -0,0 +1,141 @@
+package dynamicstruct
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// defaultTagKey is the tag key for the default values of fields (e.g. `default:"10"`).
+const defaultTagKey = "default"
+
+// InitOptions is the options for NewInterfaceWithOptions.
+type InitOptions struct {
+	// AllocPointers allocates nil pointers to structs recursively.
+	// Pointers to the struct types that are being initialised (recursive types) are left nil.
+	AllocPointers bool
+
+	// EmptyCollections makes empty slices and maps instead of nil (e.g. "[]" instead of "null" in JSON).
+	EmptyCollections bool
+
+	// Defaults sets the default values of "default" tags.
+	// A tag value is decoded as JSON, or as a JSON string if it is not valid for the field (e.g. `default:"abc"`).
+	Defaults bool
+}
+
+// NewInterfaceWithOptions returns the new interface value of built struct initialised by opts.
+// The default values in the metadata of fields are always set like NewInterface, and take precedence over the tags.
+// An error is returned if a "default" tag can not be set to the field.
+func (ds *DynamicStruct) NewInterfaceWithOptions(opts InitOptions) (interface{}, error) {
+	rv := reflect.New(ds.rt)
+	if err := ds.initialize(rv.Elem(), opts); err != nil {
+		return nil, err
+	}
+
+	if ds.isPtr {
+		return rv.Interface(), nil
+	}
+
+	return rv.Elem().Interface(), nil
+}
+
+// NewWithOptions returns a new Instance initialised by opts like NewInterfaceWithOptions.
+func (ds *DynamicStruct) NewWithOptions(opts InitOptions) (*Instance, error) {
+	rv := reflect.New(ds.rt).Elem()
+	if err := ds.initialize(rv, opts); err != nil {
+		return nil, err
+	}
+
+	return &Instance{
+		ds: ds,
+		rv: rv,
+	}, nil
+}
+
+func (ds *DynamicStruct) initialize(sv reflect.Value, opts InitOptions) error {
+	in := &initializer{
+		opts:     opts,
+		visiting: make(map[reflect.Type]bool),
+	}
+	if err := in.init(sv, ""); err != nil {
+		return err
+	}
+
+	applyDefaults(sv, ds.metas)
+
+	return nil
+}
+
+type initializer struct {
+	opts     InitOptions
+	visiting map[reflect.Type]bool // struct types in process for detecting the recursive types
+}
+
+func (in *initializer) init(v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		// opaque structs like time.Time are not initialised
+		if !hasOnlyExportedFields(v.Type()) {
+			return nil
+		}
+
+		in.visiting[v.Type()] = true
+		defer delete(in.visiting, v.Type())
+
+		for i := 0; i < v.NumField(); i++ {
+			sf := v.Type().Field(i)
+			fp := joinPath(path, sf.Name)
+			if tag, ok := sf.Tag.Lookup(defaultTagKey); ok && in.opts.Defaults {
+				if err := setDefaultTag(v.Field(i), tag, fp); err != nil {
+					return err
+				}
+			}
+			if err := in.init(v.Field(i), fp); err != nil {
+				return err
+			}
+		}
+	case reflect.Ptr:
+		et := v.Type().Elem()
+		if et.Kind() != reflect.Struct {
+			return nil
+		}
+		if v.IsNil() {
+			if !in.opts.AllocPointers || in.visiting[et] {
+				return nil
+			}
+			v.Set(reflect.New(et))
+		}
+		return in.init(v.Elem(), path)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := in.init(v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice:
+		if in.opts.EmptyCollections && v.IsNil() {
+			v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+		}
+	case reflect.Map:
+		if in.opts.EmptyCollections && v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+	}
+
+	return nil
+}
+
+func setDefaultTag(fv reflect.Value, tag string, path string) error {
+	nv := reflect.New(fv.Type())
+	if err := json.Unmarshal([]byte(tag), nv.Interface()); err != nil {
+		// decode as a string (e.g. `default:"abc"` for string fields)
+		nv = reflect.New(fv.Type())
+		if err2 := json.Unmarshal([]byte(strconv.Quote(tag)), nv.Interface()); err2 != nil {
+			return fmt.Errorf("field %s: invalid default tag %q for %v: %w", path, tag, fv.Type(), err)
+		}
+	}
+
+	fv.Set(nv.Elem())
+	return nil
+}
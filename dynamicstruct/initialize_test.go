@@ -0,0 +1,139 @@
+package dynamicstruct_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	. "github.com/goldeneggg/structil/dynamicstruct"
+)
+
+func TestNewInterfaceWithOptions(t *testing.T) {
+	t.Parallel()
+
+	nds, err := NewBuilder().
+		AddStringWithTag("Key", `json:"key" default:"k1"`).
+		AddSliceWithTag("Tags", "", `json:"tags"`).
+		BuildNonPtr()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	ds, err := NewBuilder().
+		AddStringWithTag("Name", `json:"name" default:"anonymous"`).
+		AddIntWithTag("Age", `json:"age" default:"20"`).
+		AddTypeWithTag("Rate", reflect.TypeOf((*float64)(nil)), `json:"rate" default:"0.5"`).
+		AddTypeWithTag("At", reflect.TypeOf(time.Time{}), `json:"at" default:"2020-01-02T03:04:05Z"`).
+		AddMapWithTag("Attrs", "", 0, `json:"attrs"`).
+		AddDynamicStructPtrWithTag("Obj", nds, `json:"obj"`).
+		AddDynamicStructSliceWithTag("Objs", nds, `json:"objs"`).
+		SetMeta("Age", FieldMeta{Default: 30}).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		opts InitOptions
+		want string
+	}{
+		{
+			// the default values in the metadata are always set like NewInterface
+			name: "no options",
+			want: `{"name":"","age":30,"rate":null,"at":"0001-01-01T00:00:00Z","attrs":null,"obj":null,"objs":null}`,
+		},
+		{
+			name: "AllocPointers",
+			opts: InitOptions{AllocPointers: true},
+			want: `{"name":"","age":30,"rate":null,"at":"0001-01-01T00:00:00Z","attrs":null,"obj":{"key":"","tags":null},"objs":null}`,
+		},
+		{
+			name: "EmptyCollections",
+			opts: InitOptions{EmptyCollections: true},
+			want: `{"name":"","age":30,"rate":null,"at":"0001-01-01T00:00:00Z","attrs":{},"obj":null,"objs":[]}`,
+		},
+		{
+			name: "all",
+			opts: InitOptions{AllocPointers: true, EmptyCollections: true, Defaults: true},
+			want: `{"name":"anonymous","age":30,"rate":0.5,"at":"2020-01-02T03:04:05Z","attrs":{},"obj":{"key":"k1","tags":[]},"objs":[]}`,
+		},
+	}
+
+	// NewInterfaceWithOptions without options is same as NewInterface
+	b, err := json.Marshal(ds.NewInterface())
+	if err != nil {
+		t.Fatalf("unexpected error caused by json.Marshal: %v", err)
+	}
+	if d := cmp.Diff(string(b), tests[0].want); d != "" {
+		t.Errorf("mismatch NewInterface: (-got +want)\n%s", d)
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ds.NewInterfaceWithOptions(tt.opts)
+			if err != nil {
+				t.Fatalf("unexpected error caused by NewInterfaceWithOptions: %v", err)
+			}
+			b, err := json.Marshal(got)
+			if err != nil {
+				t.Fatalf("unexpected error caused by json.Marshal: %v", err)
+			}
+			if d := cmp.Diff(string(b), tt.want); d != "" {
+				t.Errorf("mismatch: (-got +want)\n%s", d)
+			}
+		})
+	}
+}
+
+func TestNewInterfaceWithOptionsError(t *testing.T) {
+	t.Parallel()
+
+	ds, err := NewBuilder().AddIntWithTag("Age", `default:"twenty"`).Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	if _, err := ds.NewInterfaceWithOptions(InitOptions{}); err != nil {
+		t.Errorf("unexpected error without Defaults: %v", err)
+	}
+
+	_, err = ds.NewInterfaceWithOptions(InitOptions{Defaults: true})
+	if err == nil || !strings.Contains(err.Error(), `field Age: invalid default tag "twenty"`) {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+type initTestNode struct {
+	Name string
+	Next *initTestNode
+}
+
+func TestNewWithOptionsRecursiveType(t *testing.T) {
+	t.Parallel()
+
+	ds, err := NewBuilder().AddStructPtr("Root", initTestNode{}).Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	ins, err := ds.NewWithOptions(InitOptions{AllocPointers: true})
+	if err != nil {
+		t.Fatalf("unexpected error caused by NewWithOptions: %v", err)
+	}
+	// Root is an unnamed copy of initTestNode, so Root.Next is allocated but Root.Next.Next is not
+	got, err := ins.GetPath("Root.Next")
+	if err != nil {
+		t.Fatalf("unexpected error caused by GetPath: %v", err)
+	}
+	if d := cmp.Diff(got, &initTestNode{}); d != "" {
+		t.Errorf("mismatch: (-got +want)\n%s", d)
+	}
+}
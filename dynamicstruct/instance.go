@@ -0,0 +1,411 @@
+package dynamicstruct
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Instance is a value of the struct built by DynamicStruct.
+// Instance provides type-checked accessors for fields instead of raw reflection.
+type Instance struct {
+	ds *DynamicStruct
+	rv reflect.Value // addressable struct value
+}
+
+// New returns a new Instance that has the zero value of the built struct.
+// The default values in the metadata of fields are set like NewInterface.
+func (ds *DynamicStruct) New() *Instance {
+	rv := reflect.New(ds.rt).Elem()
+	applyDefaults(rv, ds.metas)
+
+	return &Instance{
+		ds: ds,
+		rv: rv,
+	}
+}
+
+// DynamicStruct returns the DynamicStruct of this.
+func (ins *Instance) DynamicStruct() *DynamicStruct {
+	return ins.ds
+}
+
+// Interface returns the struct value of this.
+// The returned value is a pointer to the struct if DynamicStruct is built by Build(), otherwise a struct.
+// Note that changes of the returned pointer are shared with this.
+func (ins *Instance) Interface() interface{} {
+	if ins.ds.isPtr {
+		return ins.rv.Addr().Interface()
+	}
+
+	return ins.rv.Interface()
+}
+
+// Get returns the value of the field with the given name.
+func (ins *Instance) Get(name string) (interface{}, error) {
+	fv, err := ins.field(ins.rv, name, name)
+	if err != nil {
+		return nil, err
+	}
+
+	return fv.Interface(), nil
+}
+
+// GetPath returns the value of the nested field with the given dot separated path (e.g. "A.B.C").
+// It returns an error if a pointer on the path is nil.
+func (ins *Instance) GetPath(path string) (interface{}, error) {
+	cur := ins.rv
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fv, err := ins.field(cur, name, strings.Join(names[:i+1], "."))
+		if err != nil {
+			return nil, err
+		}
+
+		if i == len(names)-1 {
+			return fv.Interface(), nil
+		}
+
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				return nil, fmt.Errorf("field %s is nil", strings.Join(names[:i+1], "."))
+			}
+			fv = fv.Elem()
+		}
+		cur = fv
+	}
+
+	return nil, fmt.Errorf("invalid path %q", path)
+}
+
+// Set sets v to the field with the given name.
+// v must be assignable to the field type. Numbers are converted only if the value is not changed,
+// a non-pointer value is set to a pointer field by allocating, and a map[string]interface{} is set to a struct field.
+func (ins *Instance) Set(name string, v interface{}) error {
+	fv, err := ins.field(ins.rv, name, name)
+	if err != nil {
+		return err
+	}
+
+	return assignValue(fv, v, name)
+}
+
+// SetPath sets v to the nested field with the given dot separated path (e.g. "A.B.C").
+// Nil pointers to structs on the path are allocated.
+func (ins *Instance) SetPath(path string, v interface{}) error {
+	cur := ins.rv
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		p := strings.Join(names[:i+1], ".")
+		fv, err := ins.field(cur, name, p)
+		if err != nil {
+			return err
+		}
+
+		if i == len(names)-1 {
+			return assignValue(fv, v, p)
+		}
+
+		if fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Struct {
+			if fv.IsNil() {
+				fv.Set(reflect.New(fv.Type().Elem()))
+			}
+			fv = fv.Elem()
+		}
+		cur = fv
+	}
+
+	return fmt.Errorf("invalid path %q", path)
+}
+
+// Reset sets the zero value to all fields.
+func (ins *Instance) Reset() {
+	ins.rv.Set(reflect.Zero(ins.ds.rt))
+}
+
+// Clone returns a deep copy of this.
+// Pointers, slices, maps and nested structs are copied, values in interface fields are shared.
+func (ins *Instance) Clone() *Instance {
+	c := ins.ds.New()
+	deepCopy(c.rv, ins.rv)
+	return c
+}
+
+// ToMap returns a map whose keys are field names and values are field values.
+// Nested structs (and pointers and slices of them) are converted to maps recursively, nil pointers are nil.
+func (ins *Instance) ToMap() map[string]interface{} {
+	return structToMap(ins.rv)
+}
+
+// FromMap sets the values of m to the fields whose names are the keys of m.
+// It returns an error if m has an unknown key or a value that can not be set, and then no fields are changed.
+// Fields whose names are not in m are not changed.
+func (ins *Instance) FromMap(m map[string]interface{}) error {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	// sort keys to return the same error for the same m
+	sort.Strings(keys)
+
+	// set values to a copy, and apply it only if all values are set
+	cp := reflect.New(ins.ds.rt).Elem()
+	deepCopy(cp, ins.rv)
+	for _, k := range keys {
+		fv, err := ins.field(cp, k, k)
+		if err != nil {
+			return err
+		}
+		if err := assignValue(fv, m[k], k); err != nil {
+			return err
+		}
+	}
+
+	ins.rv.Set(cp)
+	return nil
+}
+
+func (ins *Instance) field(sv reflect.Value, name string, path string) (reflect.Value, error) {
+	if sv.Kind() != reflect.Struct {
+		return reflect.Value{}, fmt.Errorf("field %s: parent type %v is not a struct", path, sv.Type())
+	}
+
+	sf, ok := sv.Type().FieldByName(name)
+	if !ok || !sf.IsExported() {
+		return reflect.Value{}, fmt.Errorf("field %s does not exist in %s", path, ins.ds.name)
+	}
+
+	return sv.FieldByIndex(sf.Index), nil
+}
+
+// assignValue sets v to dst with the type checks of Instance.Set.
+func assignValue(dst reflect.Value, v interface{}, path string) error {
+	if v == nil {
+		switch dst.Kind() {
+		case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+			dst.Set(reflect.Zero(dst.Type()))
+			return nil
+		}
+		return fmt.Errorf("field %s: nil can not be set to %v", path, dst.Type())
+	}
+
+	return assign(dst, reflect.ValueOf(v), path)
+}
+
+func assign(dst reflect.Value, src reflect.Value, path string) error {
+	dt := dst.Type()
+	st := src.Type()
+
+	if st.AssignableTo(dt) {
+		dst.Set(src)
+		return nil
+	}
+
+	// unwrap interface{} values (e.g. elements of []interface{})
+	if src.Kind() == reflect.Interface {
+		if src.IsNil() {
+			return assignValue(dst, nil, path)
+		}
+		return assign(dst, src.Elem(), path)
+	}
+
+	switch dt.Kind() {
+	case reflect.Ptr:
+		if src.Kind() == reflect.Ptr {
+			if src.IsNil() {
+				dst.Set(reflect.Zero(dt))
+				return nil
+			}
+			src = src.Elem()
+		}
+		nv := reflect.New(dt.Elem())
+		if err := assign(nv.Elem(), src, path); err != nil {
+			return err
+		}
+		dst.Set(nv)
+		return nil
+
+	case reflect.Struct:
+		if src.Kind() == reflect.Map && st.Key().Kind() == reflect.String {
+			return assignMapToStruct(dst, src, path)
+		}
+		if src.Kind() == reflect.Struct && st.ConvertibleTo(dt) {
+			dst.Set(src.Convert(dt))
+			return nil
+		}
+
+	case reflect.Slice:
+		if src.Kind() == reflect.Slice || src.Kind() == reflect.Array {
+			if src.Kind() == reflect.Slice && src.IsNil() {
+				dst.Set(reflect.Zero(dt))
+				return nil
+			}
+			nv := reflect.MakeSlice(dt, src.Len(), src.Len())
+			for i := 0; i < src.Len(); i++ {
+				if err := assign(nv.Index(i), src.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			dst.Set(nv)
+			return nil
+		}
+
+	case reflect.Map:
+		if src.Kind() == reflect.Map {
+			if src.IsNil() {
+				dst.Set(reflect.Zero(dt))
+				return nil
+			}
+			nv := reflect.MakeMapWithSize(dt, src.Len())
+			iter := src.MapRange()
+			for iter.Next() {
+				kp := fmt.Sprintf("%s[%v]", path, iter.Key())
+				nk := reflect.New(dt.Key()).Elem()
+				if err := assign(nk, iter.Key(), kp); err != nil {
+					return err
+				}
+				ne := reflect.New(dt.Elem()).Elem()
+				if err := assign(ne, iter.Value(), kp); err != nil {
+					return err
+				}
+				nv.SetMapIndex(nk, ne)
+			}
+			dst.Set(nv)
+			return nil
+		}
+
+	default:
+		if isPredeclaredNumber(dt) && isPredeclaredNumber(st) {
+			return assignNumber(dst, src, path)
+		}
+	}
+
+	return fmt.Errorf("field %s: value of type %v can not be set to %v", path, st, dt)
+}
+
+func assignMapToStruct(dst reflect.Value, src reflect.Value, path string) error {
+	nv := reflect.New(dst.Type()).Elem()
+	iter := src.MapRange()
+	for iter.Next() {
+		name := iter.Key().String()
+		sf, ok := nv.Type().FieldByName(name)
+		if !ok || !sf.IsExported() {
+			return fmt.Errorf("field %s does not exist in %v", joinPath(path, name), dst.Type())
+		}
+		if err := assign(nv.FieldByIndex(sf.Index), iter.Value(), joinPath(path, name)); err != nil {
+			return err
+		}
+	}
+
+	dst.Set(nv)
+	return nil
+}
+
+// assignNumber converts src to the number type of dst only if the value is not changed by the conversion.
+func assignNumber(dst reflect.Value, src reflect.Value, path string) error {
+	dt := dst.Type()
+	st := src.Type()
+
+	negative := (src.CanInt() && src.Int() < 0) || (src.CanFloat() && src.Float() < 0)
+	cv := src.Convert(dt)
+	if (isUnsigned(dt) && negative) || cv.Convert(st).Interface() != src.Interface() {
+		return fmt.Errorf("field %s: value %v of type %v overflows or loses precision in %v", path, src, st, dt)
+	}
+
+	dst.Set(cv)
+	return nil
+}
+
+func deepCopy(dst reflect.Value, src reflect.Value) {
+	switch src.Kind() {
+	case reflect.Ptr:
+		if src.IsNil() {
+			dst.Set(src)
+			return
+		}
+		nv := reflect.New(src.Type().Elem())
+		deepCopy(nv.Elem(), src.Elem())
+		dst.Set(nv)
+	case reflect.Slice:
+		if src.IsNil() {
+			dst.Set(src)
+			return
+		}
+		nv := reflect.MakeSlice(src.Type(), src.Len(), src.Len())
+		for i := 0; i < src.Len(); i++ {
+			deepCopy(nv.Index(i), src.Index(i))
+		}
+		dst.Set(nv)
+	case reflect.Map:
+		if src.IsNil() {
+			dst.Set(src)
+			return
+		}
+		nv := reflect.MakeMapWithSize(src.Type(), src.Len())
+		iter := src.MapRange()
+		for iter.Next() {
+			ne := reflect.New(src.Type().Elem()).Elem()
+			deepCopy(ne, iter.Value())
+			nv.SetMapIndex(iter.Key(), ne)
+		}
+		dst.Set(nv)
+	case reflect.Struct:
+		// copy unexported fields as is, and copy exported fields deeply
+		dst.Set(src)
+		for i := 0; i < src.NumField(); i++ {
+			if dst.Field(i).CanSet() {
+				deepCopy(dst.Field(i), src.Field(i))
+			}
+		}
+	case reflect.Array:
+		for i := 0; i < src.Len(); i++ {
+			deepCopy(dst.Index(i), src.Index(i))
+		}
+	default:
+		dst.Set(src)
+	}
+}
+
+func structToMap(sv reflect.Value) map[string]interface{} {
+	m := make(map[string]interface{}, sv.NumField())
+	for i := 0; i < sv.NumField(); i++ {
+		sf := sv.Type().Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+		m[sf.Name] = toMapValue(sv.Field(i))
+	}
+
+	return m
+}
+
+func toMapValue(v reflect.Value) interface{} {
+	switch v.Kind() {
+	case reflect.Struct:
+		if hasOnlyExportedFields(v.Type()) {
+			return structToMap(v)
+		}
+	case reflect.Ptr:
+		if v.Type().Elem().Kind() == reflect.Struct && hasOnlyExportedFields(v.Type().Elem()) {
+			if v.IsNil() {
+				return nil
+			}
+			return structToMap(v.Elem())
+		}
+	case reflect.Slice:
+		et := indirectType(v.Type().Elem())
+		if et.Kind() == reflect.Struct && hasOnlyExportedFields(et) {
+			if v.IsNil() {
+				return nil
+			}
+			s := make([]interface{}, v.Len())
+			for i := 0; i < v.Len(); i++ {
+				s[i] = toMapValue(v.Index(i))
+			}
+			return s
+		}
+	}
+
+	return v.Interface()
+}
@@ -0,0 +1,211 @@
+package dynamicstruct_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	. "github.com/goldeneggg/structil/dynamicstruct"
+)
+
+func newInstanceTestStruct(t *testing.T, isPtr bool) *DynamicStruct {
+	t.Helper()
+
+	nds, err := NewBuilder().
+		AddString("Key").
+		AddType("Count", reflect.TypeOf((*int)(nil))).
+		BuildNonPtr()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	b := NewBuilder().
+		AddString("Name").
+		AddType("Small", reflect.TypeOf(int8(0))).
+		AddType("Unsigned", reflect.TypeOf(uint(0))).
+		AddFloat64("Rate").
+		AddType("Nick", reflect.TypeOf((*string)(nil))).
+		AddSlice("Tags", SampleString).
+		AddMap("Attrs", SampleString, SampleInt).
+		AddInterface("Any", false).
+		AddDynamicStruct("Obj", nds, false).
+		AddDynamicStruct("ObjPtr", nds, true).
+		AddDynamicStructSlice("Objs", nds)
+
+	var ds *DynamicStruct
+	if isPtr {
+		ds, err = b.Build()
+	} else {
+		ds, err = b.BuildNonPtr()
+	}
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	return ds
+}
+
+func TestInstanceSetGet(t *testing.T) {
+	t.Parallel()
+
+	nick := "nick"
+	tests := []struct {
+		name      string
+		field     string
+		value     interface{}
+		want      interface{}
+		wantError bool
+	}{
+		{name: "string", field: "Name", value: "foo", want: "foo"},
+		{name: "int to int8", field: "Small", value: 100, want: int8(100)},
+		{name: "overflow int8", field: "Small", value: 300, wantError: true},
+		{name: "int to uint", field: "Unsigned", value: 10, want: uint(10)},
+		{name: "negative to uint", field: "Unsigned", value: -1, wantError: true},
+		{name: "int to float64", field: "Rate", value: 2, want: float64(2)},
+		{name: "integral float to int8", field: "Small", value: 2.0, want: int8(2)},
+		{name: "fractional float to int8", field: "Small", value: 2.5, wantError: true},
+		{name: "value to pointer", field: "Nick", value: "nick", want: &nick},
+		{name: "pointer to pointer", field: "Nick", value: &nick, want: &nick},
+		{name: "nil to pointer", field: "Nick", value: nil, want: (*string)(nil)},
+		{name: "nil to string", field: "Name", value: nil, wantError: true},
+		{name: "interface slice to string slice", field: "Tags", value: []interface{}{"a", "b"}, want: []string{"a", "b"}},
+		{name: "mixed slice to string slice", field: "Tags", value: []interface{}{"a", 1}, wantError: true},
+		{name: "float map to int map", field: "Attrs", value: map[string]float64{"a": 1}, want: map[string]int{"a": 1}},
+		{name: "any", field: "Any", value: 1.5, want: 1.5},
+		{name: "string to int8", field: "Small", value: "1", wantError: true},
+		{name: "unknown field", field: "Unknown", value: 1, wantError: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ins := newInstanceTestStruct(t, true).New()
+			err := ins.Set(tt.field, tt.value)
+			if err != nil {
+				if !tt.wantError {
+					t.Fatalf("unexpected error is returned from Set: %v", err)
+				}
+				return
+			} else if tt.wantError {
+				t.Fatalf("error is expected but it does not occur from Set")
+			}
+
+			got, err := ins.Get(tt.field)
+			if err != nil {
+				t.Fatalf("unexpected error is returned from Get: %v", err)
+			}
+			if d := cmp.Diff(got, tt.want); d != "" {
+				t.Fatalf("mismatch Get: (-got +want)\n%s", d)
+			}
+		})
+	}
+}
+
+func TestInstanceSetPath(t *testing.T) {
+	t.Parallel()
+
+	ds := newInstanceTestStruct(t, true)
+	ins := ds.New()
+
+	if err := ins.SetPath("ObjPtr.Count", 3); err != nil {
+		t.Fatalf("unexpected error is returned from SetPath: %v", err)
+	}
+	if err := ins.SetPath("Obj.Key", "k"); err != nil {
+		t.Fatalf("unexpected error is returned from SetPath: %v", err)
+	}
+
+	got, err := ins.GetPath("ObjPtr.Count")
+	if err != nil {
+		t.Fatalf("unexpected error is returned from GetPath: %v", err)
+	}
+	if c, ok := got.(*int); !ok || *c != 3 {
+		t.Errorf("unexpected GetPath result. got: %#v", got)
+	}
+	if got, _ := ins.GetPath("Obj.Key"); got != "k" {
+		t.Errorf("unexpected GetPath result. got: %#v", got)
+	}
+
+	if err := ins.SetPath("Obj.Unknown", 1); err == nil {
+		t.Errorf("error is expected but it does not occur from SetPath with unknown field")
+	}
+	if err := ins.SetPath("Name.Key", 1); err == nil {
+		t.Errorf("error is expected but it does not occur from SetPath through non-struct field")
+	}
+
+	ins.Reset()
+	if _, err := ins.GetPath("ObjPtr.Count"); err == nil {
+		t.Errorf("error is expected but it does not occur from GetPath through nil pointer")
+	}
+}
+
+func TestInstanceMapAndClone(t *testing.T) {
+	t.Parallel()
+
+	for _, isPtr := range []bool{true, false} {
+		ds := newInstanceTestStruct(t, isPtr)
+		ins := ds.New()
+
+		m := map[string]interface{}{
+			"Name":   "foo",
+			"Small":  float64(1),
+			"Tags":   []interface{}{"a"},
+			"Attrs":  map[string]interface{}{"x": 1},
+			"Obj":    map[string]interface{}{"Key": "k", "Count": 2},
+			"ObjPtr": map[string]interface{}{"Key": "p"},
+			"Objs":   []interface{}{map[string]interface{}{"Key": "s"}},
+		}
+		if err := ins.FromMap(m); err != nil {
+			t.Fatalf("unexpected error is returned from FromMap: %v", err)
+		}
+
+		cnt := 2
+		want := map[string]interface{}{
+			"Name":     "foo",
+			"Small":    int8(1),
+			"Unsigned": uint(0),
+			"Rate":     float64(0),
+			"Nick":     (*string)(nil),
+			"Tags":     []string{"a"},
+			"Attrs":    map[string]int{"x": 1},
+			"Any":      nil,
+			"Obj":      map[string]interface{}{"Key": "k", "Count": &cnt},
+			"ObjPtr":   map[string]interface{}{"Key": "p", "Count": (*int)(nil)},
+			"Objs":     []interface{}{map[string]interface{}{"Key": "s", "Count": (*int)(nil)}},
+		}
+		if d := cmp.Diff(ins.ToMap(), want); d != "" {
+			t.Fatalf("mismatch ToMap: (-got +want)\n%s", d)
+		}
+
+		c := ins.Clone()
+		if err := c.SetPath("Obj.Count", 5); err != nil {
+			t.Fatalf("unexpected error is returned from SetPath: %v", err)
+		}
+		if err := c.SetPath("ObjPtr.Key", "changed"); err != nil {
+			t.Fatalf("unexpected error is returned from SetPath: %v", err)
+		}
+		if d := cmp.Diff(ins.ToMap(), want); d != "" {
+			t.Fatalf("original is changed by changing clone: (-got +want)\n%s", d)
+		}
+
+		if err := ins.FromMap(map[string]interface{}{"Unknown": 1}); err == nil {
+			t.Errorf("error is expected but it does not occur from FromMap with unknown key")
+		}
+		if err := ins.FromMap(map[string]interface{}{"Obj": map[string]interface{}{"Unknown": 1}}); err == nil {
+			t.Errorf("error is expected but it does not occur from FromMap with unknown nested key")
+		}
+		// no fields are changed by the failed FromMap
+		if err := ins.FromMap(map[string]interface{}{"Name": "bar", "Small": 1000, "Unknown": 1}); err == nil {
+			t.Errorf("error is expected but it does not occur from FromMap with overflow and unknown key")
+		}
+		if d := cmp.Diff(ins.ToMap(), want); d != "" {
+			t.Fatalf("original is changed by failed FromMap: (-got +want)\n%s", d)
+		}
+
+		if got := reflect.TypeOf(ins.Interface()).Kind(); (got == reflect.Ptr) != isPtr {
+			t.Errorf("unexpected Interface kind. got: %v, isPtr: %v", got, isPtr)
+		}
+	}
+}
@@ -0,0 +1,752 @@
+package dynamicstruct
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+	jsonSchemaDefsRef = "#/$defs/"
+
+	schemaTypeNull    = "null"
+	schemaTypeBoolean = "boolean"
+	schemaTypeInteger = "integer"
+	schemaTypeNumber  = "number"
+	schemaTypeString  = "string"
+	schemaTypeArray   = "array"
+	schemaTypeObject  = "object"
+)
+
+var (
+	timeType      = reflect.TypeOf(time.Time{})
+	bytesType     = reflect.TypeOf([]byte(nil))
+	interfaceType = reflect.TypeOf((*interface{})(nil)).Elem()
+)
+
+// jsonSchema is the subset of JSON Schema (draft 2020-12) keywords used by this package.
+type jsonSchema struct {
+	Schema               string                 `json:"$schema,omitempty"`
+	Ref                  string                 `json:"$ref,omitempty"`
+	Title                string                 `json:"title,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Default              interface{}            `json:"default,omitempty"`
+	Examples             []interface{}          `json:"examples,omitempty"`
+	Deprecated           bool                   `json:"deprecated,omitempty"`
+	Type                 schemaTypes            `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	ContentEncoding      string                 `json:"contentEncoding,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	Pattern              string                 `json:"pattern,omitempty"`
+	Enum                 []interface{}          `json:"enum,omitempty"`
+	OneOf                []*jsonSchema          `json:"oneOf,omitempty"`
+	AnyOf                []*jsonSchema          `json:"anyOf,omitempty"`
+	AllOf                []*jsonSchema          `json:"allOf,omitempty"`
+	Defs                 map[string]*jsonSchema `json:"$defs,omitempty"`
+	Definitions          map[string]*jsonSchema `json:"definitions,omitempty"` // legacy "$defs"
+
+	// never is true if this is the boolean schema "false"
+	never bool
+}
+
+// UnmarshalJSON supports the boolean schemas in addition to the schema objects.
+func (s *jsonSchema) UnmarshalJSON(data []byte) error {
+	var b bool
+	if err := json.Unmarshal(data, &b); err == nil {
+		*s = jsonSchema{never: !b}
+		return nil
+	}
+
+	// plain type does not have UnmarshalJSON method
+	type plain jsonSchema
+	return json.Unmarshal(data, (*plain)(s))
+}
+
+// schemaTypes is the value of "type" keyword.
+// This is marshaled to a string if it has only one type, otherwise to an array.
+type schemaTypes []string
+
+func (st schemaTypes) MarshalJSON() ([]byte, error) {
+	if len(st) == 1 {
+		return json.Marshal(st[0])
+	}
+	return json.Marshal([]string(st))
+}
+
+func (st *schemaTypes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*st = schemaTypes{s}
+		return nil
+	}
+
+	var ss []string
+	if err := json.Unmarshal(data, &ss); err != nil {
+		return fmt.Errorf("type keyword must be a string or an array of strings: %w", err)
+	}
+	*st = schemaTypes(ss)
+
+	return nil
+}
+
+// JSONSchema returns a JSON Schema (draft 2020-12) document that describes the struct of this.
+// Property names are taken from json tags, and nested structs are defined in "$defs".
+// Pointer fields are nullable, and they are not required as well as the fields with "omitempty".
+// The metadata of fields are exported as the annotation and validation keywords (e.g. "description", "default"),
+// and the fields referring to resolved Refs are exported as "$ref" to the referred schemas.
+func (ds *DynamicStruct) JSONSchema() ([]byte, error) {
+	g := &jsonSchemaGen{
+		namer: newTypeNamer(),
+		defs:  make(map[string]*jsonSchema),
+		root:  ds,
+		cur:   ds,
+	}
+	g.namer.reserve(ds.name, ds.rt)
+
+	root, err := g.objectSchema(ds.rt, "")
+	if err != nil {
+		return nil, err
+	}
+	root.Schema = jsonSchemaDialect
+	root.Title = ds.name
+	if len(g.defs) > 0 {
+		root.Defs = g.defs
+	}
+
+	return json.MarshalIndent(root, "", "  ")
+}
+
+type jsonSchemaGen struct {
+	namer *typeNamer
+	defs  map[string]*jsonSchema
+	root  *DynamicStruct
+	cur   *DynamicStruct // the DynamicStruct that paths are relative to
+}
+
+// objectSchema returns the object schema of the struct type t.
+// path is the field path of t from the top level struct, and the metadata under it are used.
+func (g *jsonSchemaGen) objectSchema(t reflect.Type, path string) (*jsonSchema, error) {
+	s := &jsonSchema{
+		Type:       schemaTypes{schemaTypeObject},
+		Properties: make(map[string]*jsonSchema),
+	}
+
+	if err := g.addProperties(s, t, path); err != nil {
+		return nil, err
+	}
+	sort.Strings(s.Required)
+
+	return s, nil
+}
+
+func (g *jsonSchemaGen) addProperties(s *jsonSchema, t reflect.Type, path string) error {
+	// sort fields for the deterministic names of $defs
+	for _, sf := range sortFields(structFields(t)) {
+		name, opts, skip := jsonNameOf(sf)
+		if skip {
+			continue
+		}
+
+		// fields of embedded struct are promoted like encoding/json
+		if sf.Anonymous && name == "" {
+			et := sf.Type
+			if et.Kind() == reflect.Ptr {
+				et = et.Elem()
+			}
+			if et.Kind() == reflect.Struct && et.Name() == "" {
+				if err := g.addProperties(s, et, joinPath(path, sf.Name)); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+
+		if name == "" {
+			name = sf.Name
+		}
+
+		fp := joinPath(path, sf.Name)
+		var ps *jsonSchema
+		var err error
+		r, isRef := g.cur.refs[fp]
+		if isRef && r.ds != nil {
+			ps, err = g.refSchema(r, sf.Type)
+		} else {
+			ps, err = g.schemaOf(sf.Type, sf.Name, fp)
+		}
+		if err != nil {
+			return fmt.Errorf("field %s: %w", sf.Name, err)
+		}
+		ps.setMeta(g.cur.metas[fp])
+		s.Properties[name] = ps
+
+		// a single Ref is nullable like a pointer
+		isPtr := sf.Type.Kind() == reflect.Ptr || (isRef && sf.Type.Kind() == reflect.Interface)
+		if !isPtr && !strings.Contains(opts, "omitempty") {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	return nil
+}
+
+func (g *jsonSchemaGen) schemaOf(t reflect.Type, hint string, path string) (*jsonSchema, error) {
+	switch t {
+	case timeType:
+		return &jsonSchema{Type: schemaTypes{schemaTypeString}, Format: "date-time"}, nil
+	case bytesType:
+		return &jsonSchema{Type: schemaTypes{schemaTypeString}, ContentEncoding: "base64"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &jsonSchema{Type: schemaTypes{schemaTypeBoolean}}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &jsonSchema{Type: schemaTypes{schemaTypeInteger}}, nil
+	case reflect.Float32, reflect.Float64:
+		return &jsonSchema{Type: schemaTypes{schemaTypeNumber}}, nil
+	case reflect.String:
+		return &jsonSchema{Type: schemaTypes{schemaTypeString}}, nil
+	case reflect.Interface:
+		return &jsonSchema{}, nil
+	case reflect.Ptr:
+		es, err := g.schemaOf(t.Elem(), hint, path)
+		if err != nil {
+			return nil, err
+		}
+		return nullable(es), nil
+	case reflect.Slice, reflect.Array:
+		es, err := g.schemaOf(t.Elem(), hint, path)
+		if err != nil {
+			return nil, err
+		}
+		s := &jsonSchema{Type: schemaTypes{schemaTypeArray}, Items: es}
+		if t.Kind() == reflect.Array {
+			l := t.Len()
+			s.MinItems = &l
+			s.MaxItems = &l
+		}
+		return s, nil
+	case reflect.Map:
+		switch t.Key().Kind() {
+		case reflect.String,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		default:
+			return nil, fmt.Errorf("unsupported map key type %s", t.Key())
+		}
+		es, err := g.schemaOf(t.Elem(), hint, path)
+		if err != nil {
+			return nil, err
+		}
+		return &jsonSchema{Type: schemaTypes{schemaTypeObject}, AdditionalProperties: es}, nil
+	case reflect.Struct:
+		if t == g.root.rt {
+			return &jsonSchema{Ref: "#"}, nil
+		}
+		name, isNew := g.namer.nameOf(t, hint)
+		if isNew {
+			// register before recursion for the same type in nested fields
+			g.defs[name] = nil
+			os, err := g.objectSchema(t, path)
+			if err != nil {
+				return nil, err
+			}
+			g.defs[name] = os
+		}
+		return &jsonSchema{Ref: jsonSchemaDefsRef + name}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported type %s", t)
+}
+
+// refSchema returns the schema of the field of t referring to r.
+// The referred DynamicStruct is defined in "$defs" unless it is the root.
+func (g *jsonSchemaGen) refSchema(r *Ref, t reflect.Type) (*jsonSchema, error) {
+	ref := "#"
+	if r.ds != g.root {
+		name, isNew := g.namer.nameOf(r.ds.rt, r.name)
+		if isNew {
+			g.defs[name] = nil
+			cur := g.cur
+			g.cur = r.ds
+			os, err := g.objectSchema(r.ds.rt, "")
+			g.cur = cur
+			if err != nil {
+				return nil, err
+			}
+			g.defs[name] = os
+		}
+		ref = jsonSchemaDefsRef + name
+	}
+
+	if t.Kind() == reflect.Slice {
+		return &jsonSchema{Type: schemaTypes{schemaTypeArray}, Items: &jsonSchema{Ref: ref}}, nil
+	}
+	return nullable(&jsonSchema{Ref: ref}), nil
+}
+
+// setMeta sets the keywords from the metadata m.
+func (s *jsonSchema) setMeta(m FieldMeta) {
+	s.Description = m.Description
+	s.Default = m.Default
+	if m.Example != nil {
+		s.Examples = []interface{}{m.Example}
+	}
+	s.Deprecated = m.Deprecated
+	s.Minimum = m.Minimum
+	s.Maximum = m.Maximum
+	s.MinLength = m.MinLength
+	s.MaxLength = m.MaxLength
+	s.Pattern = m.Pattern
+	if len(m.Enum) > 0 {
+		s.Enum = m.Enum
+	}
+}
+
+// meta returns the metadata from the keywords of s.
+func (s *jsonSchema) meta() FieldMeta {
+	m := FieldMeta{
+		Description: s.Description,
+		Default:     s.Default,
+		Deprecated:  s.Deprecated,
+		Minimum:     s.Minimum,
+		Maximum:     s.Maximum,
+		MinLength:   s.MinLength,
+		MaxLength:   s.MaxLength,
+		Pattern:     s.Pattern,
+		Enum:        s.Enum,
+	}
+	if len(s.Examples) > 0 {
+		m.Example = s.Examples[0]
+	}
+	return m
+}
+
+// nullable returns a schema that allows null in addition to s.
+func nullable(s *jsonSchema) *jsonSchema {
+	switch {
+	case s.Ref != "":
+		return &jsonSchema{AnyOf: []*jsonSchema{s, {Type: schemaTypes{schemaTypeNull}}}}
+	case len(s.Type) == 0:
+		// empty schema already allows null
+		return s
+	}
+
+	for _, typ := range s.Type {
+		if typ == schemaTypeNull {
+			return s
+		}
+	}
+	s.Type = append(s.Type, schemaTypeNull)
+
+	return s
+}
+
+// jsonNameOf returns the name and options of json tag of sf.
+// 3rd return value will be true if the field is ignored by json tag "-".
+func jsonNameOf(sf reflect.StructField) (string, string, bool) {
+	tag, ok := sf.Tag.Lookup("json")
+	if !ok {
+		return "", "", false
+	}
+	if tag == "-" {
+		return "", "", true
+	}
+
+	name, opts, _ := strings.Cut(tag, ",")
+	return name, opts, false
+}
+
+// FromJSONSchema returns a DynamicStruct built from a JSON Schema document.
+// The root schema must be an object schema (or a reference to it).
+//
+// Each object schema with "properties" is built by Builder as a nested DynamicStruct,
+// and each property is tagged with json tag for encoding/json decoding.
+// Properties that are not required or nullable are pointers (except slices, maps and interfaces),
+// and properties that are not required have "omitempty" option.
+func FromJSONSchema(data []byte) (*DynamicStruct, error) {
+	var root jsonSchema
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("fail to unmarshal JSON Schema: %w", err)
+	}
+
+	p := &jsonSchemaParser{
+		root:     &root,
+		resolved: make(map[string]reflect.Type),
+		visiting: make(map[string]bool),
+		built:    make(map[reflect.Type]*DynamicStruct),
+		refs:     make(map[string]*Ref),
+		fieldRef: make(map[string]*Ref),
+	}
+
+	s, err := p.deref(&root)
+	if err != nil {
+		return nil, err
+	}
+	if len(s.Properties) == 0 && !s.hasType(schemaTypeObject) {
+		return nil, fmt.Errorf("root schema must be an object schema")
+	}
+
+	p.rootName = ExportedName(root.Title)
+	if p.rootName == "" {
+		p.rootName = defaultStructName
+	}
+
+	// root is built as pointer mode for decoding with ds.NewInterface()
+	ds, err := p.build(s, p.rootName, true, "")
+	if err != nil {
+		return nil, err
+	}
+	if r, ok := p.refs["#"]; ok && r.ds == nil {
+		r.Resolve(ds)
+	}
+
+	return ds, nil
+}
+
+type jsonSchemaParser struct {
+	root     *jsonSchema
+	resolved map[string]reflect.Type // resolved types by $ref
+	visiting map[string]bool         // $refs in process for detecting the recursive references
+	rootName string
+	built    map[reflect.Type]*DynamicStruct // DynamicStructs built from object schemas by their types
+	refs     map[string]*Ref                 // Refs for the recursive references keyed by $refs
+	fieldRef map[string]*Ref                 // Refs for the recursive references keyed by field paths
+}
+
+// deref returns the schema that is referenced by s.Ref (or s itself if s.Ref is empty).
+func (p *jsonSchemaParser) deref(s *jsonSchema) (*jsonSchema, error) {
+	if s.Ref == "" {
+		return s, nil
+	}
+	if s.Ref == "#" {
+		return p.root, nil
+	}
+
+	var defs map[string]*jsonSchema
+	var name string
+	switch {
+	case strings.HasPrefix(s.Ref, jsonSchemaDefsRef):
+		defs, name = p.root.Defs, strings.TrimPrefix(s.Ref, jsonSchemaDefsRef)
+	case strings.HasPrefix(s.Ref, "#/definitions/"):
+		defs, name = p.root.Definitions, strings.TrimPrefix(s.Ref, "#/definitions/")
+	default:
+		return nil, fmt.Errorf("unsupported $ref %q (only local $defs are supported)", s.Ref)
+	}
+
+	rs, ok := defs[name]
+	if !ok || rs == nil {
+		return nil, fmt.Errorf("$ref %q is not defined", s.Ref)
+	}
+
+	return rs, nil
+}
+
+func (p *jsonSchemaParser) build(s *jsonSchema, name string, isPtr bool, path string) (*DynamicStruct, error) {
+	required := make(map[string]bool, len(s.Required))
+	for _, r := range s.Required {
+		required[r] = true
+	}
+
+	props := make([]string, 0, len(s.Properties))
+	for prop := range s.Properties {
+		props = append(props, prop)
+	}
+	sort.Strings(props)
+
+	b := NewBuilder().SetStructName(name)
+	for _, prop := range props {
+		fname := ExportedName(prop)
+		if fname == "" {
+			return nil, fmt.Errorf("property %q can not be converted to a field name", prop)
+		}
+		for i := 2; b.Exists(fname); i++ {
+			fname = fmt.Sprintf("%s%d", ExportedName(prop), i)
+		}
+
+		fp := joinPath(path, fname)
+		typ, nullable, err := p.typeOf(s.Properties[prop], fname, fp)
+		if err != nil {
+			return nil, fmt.Errorf("property %q: %w", prop, err)
+		}
+
+		tag := fmt.Sprintf(`json:"%s"`, prop)
+		if !required[prop] {
+			tag = fmt.Sprintf(`json:"%s,omitempty"`, prop)
+			nullable = true
+		}
+		if nullable {
+			typ = pointerIfNeeded(typ)
+		}
+
+		b = b.AddTypeWithTag(fname, typ, tag)
+		if r, ok := p.fieldRef[fp]; ok && (typ == interfaceType || typ == interfaceSliceType) {
+			b.setRef(fname, r)
+		}
+		if nds, ok := p.built[elemStructType(typ)]; ok {
+			b.putNested(fname, nds)
+		}
+		if m := p.metaOf(s.Properties[prop], typ); !m.IsZero() {
+			b.SetMeta(fname, m)
+		}
+	}
+
+	ds, err := b.build(isPtr)
+	if err != nil {
+		return nil, err
+	}
+	p.built[ds.Type()] = ds
+
+	return ds, nil
+}
+
+// elemStructType returns the struct type of t or the elements of t (or nil if t has no struct type).
+func elemStructType(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+			t = t.Elem()
+		case reflect.Struct:
+			return t
+		default:
+			return nil
+		}
+	}
+}
+
+// metaOf returns the metadata from the keywords of s for the field of typ.
+// Default and example values that can not be set to typ are ignored.
+func (p *jsonSchemaParser) metaOf(s *jsonSchema, typ reflect.Type) FieldMeta {
+	m := s.meta()
+	if m.Default != nil && assignValue(reflect.New(typ).Elem(), m.Default, "") != nil {
+		m.Default = nil
+	}
+	if m.Example != nil && assignValue(reflect.New(typ).Elem(), m.Example, "") != nil {
+		m.Example = nil
+	}
+	return m
+}
+
+// typeOf returns the Go type for s and whether s allows null.
+// path is the field path from the top level struct.
+func (p *jsonSchemaParser) typeOf(s *jsonSchema, hint string, path string) (reflect.Type, bool, error) {
+	if s.Ref != "" {
+		return p.refTypeOf(s, hint, path)
+	}
+
+	// [X, null] style nullable
+	if len(s.AnyOf) == 2 || len(s.OneOf) == 2 {
+		alts := s.AnyOf
+		if len(alts) == 0 {
+			alts = s.OneOf
+		}
+		for i, alt := range alts {
+			if len(alt.Type) == 1 && alt.Type[0] == schemaTypeNull {
+				typ, _, err := p.typeOf(alts[1-i], hint, path)
+				return typ, true, err
+			}
+		}
+	}
+
+	if len(s.AllOf) == 1 {
+		return p.typeOf(s.AllOf[0], hint, path)
+	}
+
+	// oneOf, anyOf and allOf are unknown shapes
+	if len(s.OneOf) > 0 || len(s.AnyOf) > 0 || len(s.AllOf) > 0 {
+		return interfaceType, false, nil
+	}
+
+	types, nullable := s.nonNullTypes()
+	if len(types) == 0 {
+		switch {
+		case len(s.Properties) > 0 || s.AdditionalProperties != nil:
+			types = []string{schemaTypeObject}
+		case s.Items != nil:
+			types = []string{schemaTypeArray}
+		case len(s.Enum) > 0:
+			types, nullable = enumTypes(s.Enum)
+		}
+	}
+	if len(types) != 1 {
+		return interfaceType, nullable, nil
+	}
+
+	var typ reflect.Type
+	switch types[0] {
+	case schemaTypeBoolean:
+		typ = reflect.TypeOf(SampleBool)
+	case schemaTypeInteger:
+		typ = reflect.TypeOf(int64(0))
+	case schemaTypeNumber:
+		typ = reflect.TypeOf(SampleFloat64)
+	case schemaTypeString:
+		switch {
+		case s.Format == "date-time":
+			typ = timeType
+		case s.ContentEncoding == "base64":
+			typ = bytesType
+		default:
+			typ = reflect.TypeOf(SampleString)
+		}
+	case schemaTypeArray:
+		et := interfaceType
+		if s.Items != nil {
+			var err error
+			if et, _, err = p.typeOf(s.Items, hint, path); err != nil {
+				return nil, false, err
+			}
+		}
+		typ = reflect.SliceOf(et)
+	case schemaTypeObject:
+		switch {
+		case len(s.Properties) > 0:
+			ds, err := p.build(s, hint, false, path)
+			if err != nil {
+				return nil, false, err
+			}
+			typ = ds.Type()
+		case s.AdditionalProperties != nil && !s.AdditionalProperties.never:
+			et, _, err := p.typeOf(s.AdditionalProperties, hint, path)
+			if err != nil {
+				return nil, false, err
+			}
+			typ = reflect.MapOf(reflect.TypeOf(SampleString), et)
+		default:
+			typ = reflect.MapOf(reflect.TypeOf(SampleString), interfaceType)
+		}
+	default:
+		return nil, false, fmt.Errorf("unsupported type %q", types[0])
+	}
+
+	return typ, nullable, nil
+}
+
+func (p *jsonSchemaParser) refTypeOf(s *jsonSchema, hint string, path string) (reflect.Type, bool, error) {
+	if typ, ok := p.resolved[s.Ref]; ok {
+		return typ, false, nil
+	}
+
+	// recursive references can not be expressed by reflect.StructOf,
+	// so fallback to interface{} with the Ref to the referred schema
+	if p.visiting[s.Ref] || s.Ref == "#" {
+		r, ok := p.refs[s.Ref]
+		if !ok {
+			name := p.rootName
+			if s.Ref != "#" {
+				name = ExportedName(s.Ref[strings.LastIndex(s.Ref, "/")+1:])
+			}
+			r = NewRef(name)
+			p.refs[s.Ref] = r
+		}
+		p.fieldRef[path] = r
+		return interfaceType, false, nil
+	}
+
+	rs, err := p.deref(s)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if name := s.Ref[strings.LastIndex(s.Ref, "/")+1:]; ExportedName(name) != "" {
+		hint = ExportedName(name)
+	}
+
+	p.visiting[s.Ref] = true
+	typ, nullable, err := p.typeOf(rs, hint, path)
+	delete(p.visiting, s.Ref)
+	if err != nil {
+		return nil, false, err
+	}
+	p.resolved[s.Ref] = typ
+	if r, ok := p.refs[s.Ref]; ok && r.ds == nil {
+		if ds, ok := p.built[typ]; ok {
+			r.Resolve(ds)
+		}
+	}
+
+	return typ, nullable, nil
+}
+
+func (s *jsonSchema) hasType(typ string) bool {
+	for _, t := range s.Type {
+		if t == typ {
+			return true
+		}
+	}
+	return false
+}
+
+// nonNullTypes returns the types except for "null" and whether "null" is contained.
+func (s *jsonSchema) nonNullTypes() ([]string, bool) {
+	types := make([]string, 0, len(s.Type))
+	var nullable bool
+	for _, t := range s.Type {
+		if t == schemaTypeNull {
+			nullable = true
+			continue
+		}
+		types = append(types, t)
+	}
+	return types, nullable
+}
+
+// enumTypes returns the types inferred from enum values and whether null is contained.
+func enumTypes(enum []interface{}) ([]string, bool) {
+	set := make(map[string]bool)
+	var nullable bool
+	for _, e := range enum {
+		switch v := e.(type) {
+		case nil:
+			nullable = true
+		case bool:
+			set[schemaTypeBoolean] = true
+		case string:
+			set[schemaTypeString] = true
+		case float64:
+			if v == float64(int64(v)) {
+				set[schemaTypeInteger] = true
+			} else {
+				set[schemaTypeNumber] = true
+			}
+		default:
+			set[""] = true
+		}
+	}
+
+	// integers are widened to numbers
+	if set[schemaTypeInteger] && set[schemaTypeNumber] {
+		delete(set, schemaTypeInteger)
+	}
+
+	types := make([]string, 0, len(set))
+	for t := range set {
+		types = append(types, t)
+	}
+
+	return types, nullable
+}
+
+// pointerIfNeeded returns a pointer type of typ if typ can not express nil.
+func pointerIfNeeded(typ reflect.Type) reflect.Type {
+	switch typ.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return typ
+	}
+	return reflect.PtrTo(typ)
+}
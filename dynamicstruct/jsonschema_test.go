@@ -0,0 +1,448 @@
+package dynamicstruct_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/goldeneggg/structil"
+	. "github.com/goldeneggg/structil/dynamicstruct"
+)
+
+func TestJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	nested, err := NewBuilder().
+		AddStringWithTag("Key", `json:"key"`).
+		AddSliceWithTag("Values", SampleFloat64, `json:"values,omitempty"`).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by nested Build: %v", err)
+	}
+
+	type args struct {
+		builder *Builder
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantSchema string
+		wantError  bool
+	}{
+		{
+			name: "primitive fields",
+			args: args{
+				builder: NewBuilder().
+					SetStructName("Primitive").
+					AddStringWithTag("StringField", `json:"string_field"`).
+					AddIntWithTag("IntField", `json:"int_field,omitempty"`).
+					AddFloat32("Float32Field").
+					AddBoolWithTag("BoolField", `json:"bool_field"`).
+					AddInterfaceWithTag("InterfaceField", false, `json:"interface_field"`).
+					AddStringWithTag("IgnoredField", `json:"-"`),
+			},
+			wantSchema: `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "Primitive",
+  "type": "object",
+  "properties": {
+    "Float32Field": {
+      "type": "number"
+    },
+    "bool_field": {
+      "type": "boolean"
+    },
+    "int_field": {
+      "type": "integer"
+    },
+    "interface_field": {},
+    "string_field": {
+      "type": "string"
+    }
+  },
+  "required": [
+    "Float32Field",
+    "bool_field",
+    "interface_field",
+    "string_field"
+  ]
+}`,
+		},
+		{
+			name: "collections and well-known types",
+			args: args{
+				builder: NewBuilder().
+					AddSliceWithTag("Times", time.Time{}, `json:"times"`).
+					AddSliceWithTag("Bytes", SampleByte, `json:"bytes"`).
+					AddMapWithTag("Map", SampleString, SampleInt, `json:"map"`).
+					AddInterfaceWithTag("InterfacePtr", true, `json:"interface_ptr"`),
+			},
+			wantSchema: `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "DynamicStruct",
+  "type": "object",
+  "properties": {
+    "bytes": {
+      "type": "string",
+      "contentEncoding": "base64"
+    },
+    "interface_ptr": {},
+    "map": {
+      "type": "object",
+      "additionalProperties": {
+        "type": "integer"
+      }
+    },
+    "times": {
+      "type": "array",
+      "items": {
+        "type": "string",
+        "format": "date-time"
+      }
+    }
+  },
+  "required": [
+    "bytes",
+    "map",
+    "times"
+  ]
+}`,
+		},
+		{
+			name: "nested DynamicStructs",
+			args: args{
+				builder: NewBuilder().
+					AddDynamicStructWithTag("Item", nested, false, `json:"item"`).
+					AddDynamicStructPtrWithTag("ItemPtr", nested, `json:"item_ptr"`).
+					AddDynamicStructSliceWithTag("Items", nested, `json:"items"`),
+			},
+			wantSchema: `{
+  "$schema": "https://json-schema.org/draft/2020-12/schema",
+  "title": "DynamicStruct",
+  "type": "object",
+  "properties": {
+    "item": {
+      "$ref": "#/$defs/Item"
+    },
+    "item_ptr": {
+      "anyOf": [
+        {
+          "$ref": "#/$defs/Item"
+        },
+        {
+          "type": "null"
+        }
+      ]
+    },
+    "items": {
+      "type": "array",
+      "items": {
+        "anyOf": [
+          {
+            "$ref": "#/$defs/Item"
+          },
+          {
+            "type": "null"
+          }
+        ]
+      }
+    }
+  },
+  "required": [
+    "item",
+    "items"
+  ],
+  "$defs": {
+    "Item": {
+      "type": "object",
+      "properties": {
+        "key": {
+          "type": "string"
+        },
+        "values": {
+          "type": "array",
+          "items": {
+            "type": "number"
+          }
+        }
+      },
+      "required": [
+        "key"
+      ]
+    }
+  }
+}`,
+		},
+		{
+			name: "func field is unsupported",
+			args: args{
+				builder: NewBuilder().
+					AddFunc("FuncField", nil, nil),
+			},
+			wantError: true,
+		},
+		{
+			name: "chan field is unsupported",
+			args: args{
+				builder: NewBuilder().
+					AddChanBoth("ChanField", SampleInt),
+			},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ds, err := tt.args.builder.Build()
+			if err != nil {
+				t.Fatalf("unexpected error caused by Build: %v", err)
+			}
+
+			schema, err := ds.JSONSchema()
+			if err != nil {
+				if !tt.wantError {
+					t.Fatalf("unexpected error is returned from JSONSchema: %v", err)
+				}
+				return
+			} else if tt.wantError {
+				t.Fatalf("error is expected but it does not occur from JSONSchema. schema:\n%s", schema)
+			}
+
+			if d := cmp.Diff(string(schema), tt.wantSchema); d != "" {
+				t.Fatalf("mismatch JSONSchema: (-got +want)\n%s", d)
+			}
+		})
+	}
+}
+
+func TestFromJSONSchema(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		schema string
+	}
+	tests := []struct {
+		name           string
+		args           args
+		wantDefinition string
+		wantError      bool
+	}{
+		{
+			name: "primitive properties",
+			args: args{schema: `{
+  "title": "user",
+  "type": "object",
+  "properties": {
+    "id": {"type": "integer"},
+    "name": {"type": "string"},
+    "score": {"type": "number"},
+    "active": {"type": "boolean"},
+    "created_at": {"type": "string", "format": "date-time"},
+    "nickname": {"type": ["string", "null"]},
+    "any": {}
+  },
+  "required": ["id", "name", "score", "active", "created_at", "nickname", "any"]
+}`},
+			wantDefinition: `type User struct {
+	Active bool ` + "`json:\"active\"`" + `
+	Any interface {} ` + "`json:\"any\"`" + `
+	CreatedAt time.Time ` + "`json:\"created_at\"`" + `
+	ID int64 ` + "`json:\"id\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+	Nickname *string ` + "`json:\"nickname\"`" + `
+	Score float64 ` + "`json:\"score\"`" + `
+}`,
+		},
+		{
+			name: "optional properties are pointers with omitempty",
+			args: args{schema: `{
+  "type": "object",
+  "properties": {
+    "id": {"type": "integer"},
+    "tags": {"type": "array", "items": {"type": "string"}},
+    "labels": {"type": "object", "additionalProperties": {"type": "string"}}
+  }
+}`},
+			wantDefinition: `type DynamicStruct struct {
+	ID *int64 ` + "`json:\"id,omitempty\"`" + `
+	Labels map[string]string ` + "`json:\"labels,omitempty\"`" + `
+	Tags []string ` + "`json:\"tags,omitempty\"`" + `
+}`,
+		},
+		{
+			name: "enum, oneOf and $ref to $defs",
+			args: args{schema: `{
+  "type": "object",
+  "properties": {
+    "status": {"enum": ["active", "inactive"]},
+    "level": {"enum": [1, 2, 3]},
+    "value": {"oneOf": [{"type": "string"}, {"type": "integer"}]},
+    "item": {"$ref": "#/$defs/item"},
+    "items": {"type": "array", "items": {"$ref": "#/$defs/item"}}
+  },
+  "required": ["status", "level", "value", "item", "items"],
+  "$defs": {
+    "item": {
+      "type": "object",
+      "properties": {
+        "key": {"type": "string"}
+      },
+      "required": ["key"]
+    }
+  }
+}`},
+			wantDefinition: `type DynamicStruct struct {
+	Item struct {
+		Key string ` + "`json:\"key\"`" + `
+	} ` + "`json:\"item\"`" + `
+	Items []struct {
+		Key string ` + "`json:\"key\"`" + `
+	} ` + "`json:\"items\"`" + `
+	Level int64 ` + "`json:\"level\"`" + ` // enum: [1 2 3]
+	Status string ` + "`json:\"status\"`" + ` // enum: [active inactive]
+	Value interface {} ` + "`json:\"value\"`" + `
+}`,
+		},
+		{
+			name: "recursive $ref falls back to interface with Ref",
+			args: args{schema: `{
+  "type": "object",
+  "properties": {
+    "name": {"type": "string"},
+    "children": {"type": "array", "items": {"$ref": "#"}}
+  },
+  "required": ["name", "children"]
+}`},
+			wantDefinition: `type DynamicStruct struct {
+	Children []interface {} ` + "`json:\"children\"`" + ` // ref: DynamicStruct
+	Name string ` + "`json:\"name\"`" + `
+}`,
+		},
+		{
+			name:      "undefined $ref",
+			args:      args{schema: `{"type": "object", "properties": {"item": {"$ref": "#/$defs/none"}}}`},
+			wantError: true,
+		},
+		{
+			name:      "remote $ref",
+			args:      args{schema: `{"type": "object", "properties": {"item": {"$ref": "https://example.com/item.json"}}}`},
+			wantError: true,
+		},
+		{
+			name:      "root is not an object",
+			args:      args{schema: `{"type": "string"}`},
+			wantError: true,
+		},
+		{
+			name:      "invalid JSON",
+			args:      args{schema: `{"type": `},
+			wantError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ds, err := FromJSONSchema([]byte(tt.args.schema))
+			if err != nil {
+				if !tt.wantError {
+					t.Fatalf("unexpected error is returned from FromJSONSchema: %v", err)
+				}
+				return
+			} else if tt.wantError {
+				t.Fatalf("error is expected but it does not occur from FromJSONSchema. definition:\n%s", ds.Definition())
+			}
+
+			if d := cmp.Diff(ds.Definition(), tt.wantDefinition); d != "" {
+				t.Fatalf("mismatch Definition: (-got +want)\n%s", d)
+			}
+		})
+	}
+}
+
+func TestFromJSONSchemaDecode(t *testing.T) {
+	t.Parallel()
+
+	ds, err := FromJSONSchema([]byte(`{
+  "type": "object",
+  "properties": {
+    "id": {"type": "integer"},
+    "created_at": {"type": "string", "format": "date-time"},
+    "item": {"type": "object", "properties": {"key": {"type": "string"}}}
+  },
+  "required": ["id", "created_at"]
+}`))
+	if err != nil {
+		t.Fatalf("unexpected error is returned from FromJSONSchema: %v", err)
+	}
+
+	intf := ds.NewInterface()
+	if err := json.Unmarshal([]byte(`{"id":9007199254740993,"created_at":"2020-01-02T03:04:05Z","item":{"key":"k"}}`), &intf); err != nil {
+		t.Fatalf("unexpected error is returned from json.Unmarshal: %v", err)
+	}
+
+	g, err := structil.NewGetter(intf)
+	if err != nil {
+		t.Fatalf("unexpected error is returned from NewGetter: %v", err)
+	}
+	if id, _ := g.Int64("ID"); id != 9007199254740993 {
+		t.Errorf("unexpected ID. got: %d", id)
+	}
+	if ca, _ := g.Get("CreatedAt"); !ca.(time.Time).Equal(time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("unexpected CreatedAt. got: %v", ca)
+	}
+	gi, ok := g.GetGetter("Item")
+	if !ok {
+		t.Fatalf("GetGetter(Item) is unexpectedly failed")
+	}
+	if k, _ := gi.String("Key"); k != "k" {
+		t.Errorf("unexpected Item.Key. got: %s", k)
+	}
+}
+
+func TestJSONSchemaRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	nested, _ := NewBuilder().
+		AddStringWithTag("Key", `json:"key"`).
+		Build()
+	ds, err := NewBuilder().
+		SetStructName("Root").
+		AddIntWithTag("ID", `json:"id"`).
+		AddSliceWithTag("Times", time.Time{}, `json:"times"`).
+		AddDynamicStructWithTag("Item", nested, false, `json:"item"`).
+		AddMapWithTag("Labels", SampleString, SampleString, `json:"labels"`).
+		BuildNonPtr()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	schema, err := ds.JSONSchema()
+	if err != nil {
+		t.Fatalf("unexpected error is returned from JSONSchema: %v", err)
+	}
+
+	rds, err := FromJSONSchema(schema)
+	if err != nil {
+		t.Fatalf("unexpected error is returned from FromJSONSchema: %v", err)
+	}
+
+	want := `type Root struct {
+	ID int64 ` + "`json:\"id\"`" + `
+	Item struct {
+		Key string ` + "`json:\"key\"`" + `
+	} ` + "`json:\"item\"`" + `
+	Labels map[string]string ` + "`json:\"labels\"`" + `
+	Times []time.Time ` + "`json:\"times\"`" + `
+}`
+	if d := cmp.Diff(rds.Definition(), want); d != "" {
+		t.Fatalf("mismatch Definition: (-got +want)\n%s", d)
+	}
+}
@@ -0,0 +1,446 @@
+package dynamicstruct
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// ConflictPolicy is the policy to resolve the conflict of field types between two DynamicStructs.
+type ConflictPolicy int
+
+const (
+	// PolicyWiden widens the conflicted types (e.g. int and float64 to float64, T and interface{} to *T).
+	// If types can not be widened, interface{} is used.
+	PolicyWiden ConflictPolicy = iota
+
+	// PolicyLeft uses the type of the left side DynamicStruct.
+	PolicyLeft
+
+	// PolicyRight uses the type of the right side DynamicStruct.
+	PolicyRight
+
+	// PolicyInterface uses interface{} for the conflicted types.
+	PolicyInterface
+
+	// PolicyError returns an error if types can not be widened.
+	PolicyError
+)
+
+// MergeOptions is the options for Merge and Intersect.
+type MergeOptions struct {
+	// Policy is the policy to resolve the type conflicts.
+	Policy ConflictPolicy
+
+	// Optional makes the fields that exist in only one side optional.
+	// Optional fields are pointers, and "omitempty" option is added to their json, yaml, toml and xml tags.
+	Optional bool
+}
+
+// Conflict is the information of a conflicted field and its resolution.
+type Conflict struct {
+	// Path is the field path from the top level struct (e.g. "ObjField.Id").
+	Path string
+
+	// Left and Right are the field types of each side.
+	Left  reflect.Type
+	Right reflect.Type
+
+	// LeftTag and RightTag are the field tags of each side.
+	LeftTag  reflect.StructTag
+	RightTag reflect.StructTag
+
+	// Result is the resolved field type.
+	Result reflect.Type
+
+	// ResultTag is the resolved field tag.
+	ResultTag reflect.StructTag
+}
+
+// String returns the human readable description of this.
+func (c Conflict) String() string {
+	var msgs []string
+	if c.Left != c.Right {
+		msgs = append(msgs, fmt.Sprintf("type %v and %v are resolved to %v", c.Left, c.Right, c.Result))
+	}
+	if c.LeftTag != c.RightTag {
+		msgs = append(msgs, fmt.Sprintf("tag %q and %q are resolved to %q", c.LeftTag, c.RightTag, c.ResultTag))
+	}
+	return c.Path + ": " + strings.Join(msgs, ", ")
+}
+
+// Merge returns a new DynamicStruct that has the union of fields of a and b.
+// Fields of a are ordered first, and fields that exist only in b follow them.
+// The types of fields that exist in both are resolved by opts.Policy,
+// and nested structs are merged recursively.
+// The name and the pointer mode of the result are same as a.
+// Metadata and Refs of fields are kept, and those of a take precedence.
+func Merge(a *DynamicStruct, b *DynamicStruct, opts MergeOptions) (*DynamicStruct, []Conflict, error) {
+	m := &merger{opts: opts}
+	fields, err := m.mergeFields(a.fields, b.fields, "", true)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ds, err := buildFrom(a, b, fields)
+	return ds, m.conflicts, err
+}
+
+// Intersect returns a new DynamicStruct that has the fields which exist in both a and b.
+// The types of fields are resolved by opts.Policy, and nested structs are intersected recursively.
+// The name and the pointer mode of the result are same as a.
+// Metadata and Refs of fields are kept, and those of a take precedence.
+func Intersect(a *DynamicStruct, b *DynamicStruct, opts MergeOptions) (*DynamicStruct, []Conflict, error) {
+	m := &merger{opts: opts}
+	fields, err := m.mergeFields(a.fields, b.fields, "", false)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ds, err := buildFrom(a, b, fields)
+	return ds, m.conflicts, err
+}
+
+// Subtract returns a new DynamicStruct that has the fields of a which do not exist in b.
+// Fields are compared by name at the top level only.
+// The name and the pointer mode of the result are same as a, and metadata and Refs of the remaining fields are kept.
+func Subtract(a *DynamicStruct, b *DynamicStruct) (*DynamicStruct, error) {
+	bNames := make(map[string]bool, len(b.fields))
+	for _, f := range b.fields {
+		bNames[f.Name] = true
+	}
+
+	fields := make([]reflect.StructField, 0, len(a.fields))
+	for _, f := range a.fields {
+		if !bNames[f.Name] {
+			fields = append(fields, f)
+		}
+	}
+
+	return buildFrom(a, nil, fields)
+}
+
+// buildFrom builds fields with the name and the pointer mode of base.
+// Metadata and Refs of base are kept like ToBuilder, and those of other are added for the fields that base does not have.
+// Refs are kept only if the fields still have the types of Refs.
+func buildFrom(base *DynamicStruct, other *DynamicStruct, fields []reflect.StructField) (*DynamicStruct, error) {
+	metas := make(map[string]FieldMeta, len(base.metas))
+	refs := make(map[string]*Ref, len(base.refs))
+	for _, ds := range []*DynamicStruct{other, base} {
+		if ds == nil {
+			continue
+		}
+		for p, m := range ds.metas {
+			metas[p] = m
+		}
+		for p, r := range ds.refs {
+			refs[p] = r
+		}
+	}
+
+	b := NewBuilder().SetStructName(base.name)
+	for _, f := range fields {
+		b = b.AddTypeWithTag(f.Name, f.Type, string(f.Tag))
+	}
+	for _, f := range fields {
+		bf := b.getFieldMap(f.Name)
+		if bf == nil {
+			continue
+		}
+
+		bf.meta = metas[f.Name]
+		for p, m := range subMetas(metas, f.Name) {
+			if _, ok := fieldTypeByPath(f.Type, p); ok {
+				if bf.nestedMetas == nil {
+					bf.nestedMetas = make(map[string]FieldMeta)
+				}
+				bf.nestedMetas[p] = m
+			}
+		}
+
+		if isRefType(f.Type) {
+			bf.ref = refs[f.Name]
+		}
+		for p, r := range subRefs(refs, f.Name) {
+			if t, ok := fieldTypeByPath(f.Type, p); ok && isRefType(t) {
+				if bf.nestedRefs == nil {
+					bf.nestedRefs = make(map[string]*Ref)
+				}
+				bf.nestedRefs[p] = r
+			}
+		}
+	}
+
+	return b.build(base.isPtr)
+}
+
+func isRefType(typ reflect.Type) bool {
+	return typ == interfaceType || typ == interfaceSliceType
+}
+
+type merger struct {
+	opts      MergeOptions
+	conflicts []Conflict
+}
+
+func (m *merger) mergeFields(as []reflect.StructField, bs []reflect.StructField, path string, union bool) ([]reflect.StructField, error) {
+	bIdx := make(map[string]int, len(bs))
+	for i, f := range bs {
+		bIdx[f.Name] = i
+	}
+
+	res := make([]reflect.StructField, 0, len(as)+len(bs))
+	seen := make(map[string]bool, len(as))
+	for _, af := range as {
+		seen[af.Name] = true
+
+		i, ok := bIdx[af.Name]
+		if !ok {
+			if union {
+				res = append(res, m.onlyOneSide(af))
+			}
+			continue
+		}
+
+		f, err := m.mergeField(af, bs[i], joinPath(path, af.Name), union)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, f)
+	}
+
+	if union {
+		for _, bf := range bs {
+			if !seen[bf.Name] {
+				res = append(res, m.onlyOneSide(bf))
+			}
+		}
+	}
+
+	return res, nil
+}
+
+func (m *merger) onlyOneSide(f reflect.StructField) reflect.StructField {
+	if m.opts.Optional {
+		f.Type = pointerIfNeeded(f.Type)
+		f.Tag = withOmitempty(f.Tag)
+	}
+	return f
+}
+
+func (m *merger) mergeField(af reflect.StructField, bf reflect.StructField, path string, union bool) (reflect.StructField, error) {
+	typ, err := m.mergeType(af.Type, bf.Type, path, union)
+	if err != nil {
+		return reflect.StructField{}, err
+	}
+
+	tag := af.Tag
+	if m.opts.Policy == PolicyRight {
+		tag = bf.Tag
+	}
+
+	// nested struct conflicts are recorded in the recursive calls
+	if af.Tag != bf.Tag || (af.Type != bf.Type && !isMergeableStructs(af.Type, bf.Type)) {
+		m.conflicts = append(m.conflicts, Conflict{
+			Path:      path,
+			Left:      af.Type,
+			Right:     bf.Type,
+			LeftTag:   af.Tag,
+			RightTag:  bf.Tag,
+			Result:    typ,
+			ResultTag: tag,
+		})
+	}
+
+	af.Type = typ
+	af.Tag = tag
+	return af, nil
+}
+
+func (m *merger) mergeType(at reflect.Type, bt reflect.Type, path string, union bool) (reflect.Type, error) {
+	if at == bt {
+		return at, nil
+	}
+
+	if isMergeableStructs(at, bt) {
+		fields, err := m.mergeFields(structFields(at), structFields(bt), path, union)
+		if err != nil {
+			return nil, err
+		}
+		return structOf(fields)
+	}
+
+	switch m.opts.Policy {
+	case PolicyLeft:
+		return at, nil
+	case PolicyRight:
+		return bt, nil
+	case PolicyInterface:
+		return interfaceType, nil
+	}
+
+	if typ, ok := m.widen(at, bt, path, union); ok {
+		return typ, nil
+	}
+
+	if m.opts.Policy == PolicyError {
+		return nil, fmt.Errorf("field %s: type %v and %v can not be widened", path, at, bt)
+	}
+
+	return interfaceType, nil
+}
+
+// widen returns a type that can hold both values of at and bt.
+func (m *merger) widen(at reflect.Type, bt reflect.Type, path string, union bool) (reflect.Type, bool) {
+	if at == bt {
+		return at, true
+	}
+
+	// nil (e.g. JSON null) is decoded to interface{}, so interface{} and T are widened to nullable T
+	if at == interfaceType {
+		return pointerIfNeeded(bt), true
+	}
+	if bt == interfaceType {
+		return pointerIfNeeded(at), true
+	}
+
+	if at.Kind() == reflect.Ptr || bt.Kind() == reflect.Ptr {
+		et, err := m.mergeType(indirectType(at), indirectType(bt), path, union)
+		if err != nil || et == interfaceType {
+			return nil, false
+		}
+		return pointerIfNeeded(et), true
+	}
+
+	if isPredeclaredNumber(at) && isPredeclaredNumber(bt) {
+		return widenNumber(at, bt), true
+	}
+
+	if at.Kind() != bt.Kind() {
+		return nil, false
+	}
+
+	switch at.Kind() {
+	case reflect.Slice:
+		et, err := m.mergeType(at.Elem(), bt.Elem(), path, union)
+		if err != nil {
+			return nil, false
+		}
+		return reflect.SliceOf(et), true
+	case reflect.Map:
+		if at.Key() != bt.Key() {
+			return nil, false
+		}
+		et, err := m.mergeType(at.Elem(), bt.Elem(), path, union)
+		if err != nil {
+			return nil, false
+		}
+		return reflect.MapOf(at.Key(), et), true
+	}
+
+	return nil, false
+}
+
+func isMergeableStructs(at reflect.Type, bt reflect.Type) bool {
+	return at.Kind() == reflect.Struct && bt.Kind() == reflect.Struct &&
+		at.Name() == "" && bt.Name() == ""
+}
+
+func isPredeclaredNumber(t reflect.Type) bool {
+	if t.PkgPath() != "" {
+		return false
+	}
+
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func isFloat(t reflect.Type) bool {
+	return t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64
+}
+
+func isUnsigned(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+// widenNumber returns float64 if either is float, uint64 if both are unsigned integers,
+// and int64 if both are signed integers or the unsigned one is narrower than 64 bits.
+// Otherwise (e.g. int64 and uint64) neither int64 nor uint64 can hold both values, so float64 is returned.
+func widenNumber(at reflect.Type, bt reflect.Type) reflect.Type {
+	switch {
+	case isFloat(at) || isFloat(bt):
+		return reflect.TypeOf(float64(0))
+	case isUnsigned(at) && isUnsigned(bt):
+		return reflect.TypeOf(uint64(0))
+	case isUnsigned(at) && at.Bits() == 64, isUnsigned(bt) && bt.Bits() == 64:
+		return reflect.TypeOf(float64(0))
+	default:
+		return reflect.TypeOf(int64(0))
+	}
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+func structOf(fields []reflect.StructField) (typ reflect.Type, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("fail to reflect.StructOf: %v", r)
+		}
+	}()
+
+	return reflect.StructOf(fields), nil
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// withOmitempty returns a tag that "omitempty" option is added to json, yaml, toml and xml tags.
+// xml tags of the fields other than elements and attributes (e.g. ",chardata") are not changed
+// because encoding/xml rejects "omitempty" for them.
+func withOmitempty(tag reflect.StructTag) reflect.StructTag {
+	t, err := ParseTag(string(tag))
+	if err != nil || len(t.Keys()) == 0 {
+		return tag
+	}
+
+	for _, key := range []string{"json", "yaml", "toml"} {
+		t.AddOption(key, "omitempty")
+	}
+	if isXMLElementOrAttr(t) {
+		t.AddOption("xml", "omitempty")
+	}
+	return t.StructTag()
+}
+
+func isXMLElementOrAttr(t *Tag) bool {
+	v, ok := t.Get("xml")
+	if !ok {
+		return false
+	}
+
+	opts := strings.Split(v, ",")
+	for _, o := range opts[1:] {
+		if o != "attr" && o != "omitempty" {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,405 @@
+package dynamicstruct_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	. "github.com/goldeneggg/structil/dynamicstruct"
+)
+
+func newMergeTestStructs(t *testing.T) (*DynamicStruct, *DynamicStruct) {
+	t.Helper()
+
+	na, err := NewBuilder().
+		AddStringWithTag("Key", `json:"key"`).
+		BuildNonPtr()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+	nb, err := NewBuilder().
+		AddStringWithTag("Key", `json:"key"`).
+		AddIntWithTag("Count", `json:"count"`).
+		BuildNonPtr()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	a, err := NewBuilder().
+		SetStructName("Left").
+		AddIntWithTag("ID", `json:"id"`).
+		AddStringWithTag("Name", `json:"name"`).
+		AddInterfaceWithTag("Note", false, `json:"note"`).
+		AddSliceWithTag("Scores", SampleInt, `json:"scores"`).
+		AddDynamicStructWithTag("Item", na, false, `json:"item"`).
+		AddBoolWithTag("OnlyLeft", `json:"only_left"`).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+	b, err := NewBuilder().
+		SetStructName("Right").
+		AddFloat64WithTag("ID", `json:"id"`).
+		AddStringWithTag("Name", `json:"full_name"`).
+		AddStringWithTag("Note", `json:"note"`).
+		AddSliceWithTag("Scores", SampleFloat64, `json:"scores"`).
+		AddDynamicStructWithTag("Item", nb, false, `json:"item"`).
+		AddStringWithTag("OnlyRight", `json:"only_right"`).
+		BuildNonPtr()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	return a, b
+}
+
+func TestMerge(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name           string
+		opts           MergeOptions
+		wantDefinition string
+		wantConflicts  []string
+		wantError      bool
+	}{
+		{
+			name: "widen",
+			opts: MergeOptions{},
+			wantDefinition: `type Left struct {
+	ID float64 ` + "`json:\"id\"`" + `
+	Item struct {
+		Count int ` + "`json:\"count\"`" + `
+		Key string ` + "`json:\"key\"`" + `
+	} ` + "`json:\"item\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+	Note *string ` + "`json:\"note\"`" + `
+	OnlyLeft bool ` + "`json:\"only_left\"`" + `
+	OnlyRight string ` + "`json:\"only_right\"`" + `
+	Scores []float64 ` + "`json:\"scores\"`" + `
+}`,
+			wantConflicts: []string{
+				"ID: type int and float64 are resolved to float64",
+				`Name: tag "json:\"name\"" and "json:\"full_name\"" are resolved to "json:\"name\""`,
+				"Note: type interface {} and string are resolved to *string",
+				"Scores: type []int and []float64 are resolved to []float64",
+			},
+		},
+		{
+			name: "widen with optional",
+			opts: MergeOptions{Optional: true},
+			wantDefinition: `type Left struct {
+	ID float64 ` + "`json:\"id\"`" + `
+	Item struct {
+		Count *int ` + "`json:\"count,omitempty\"`" + `
+		Key string ` + "`json:\"key\"`" + `
+	} ` + "`json:\"item\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+	Note *string ` + "`json:\"note\"`" + `
+	OnlyLeft *bool ` + "`json:\"only_left,omitempty\"`" + `
+	OnlyRight *string ` + "`json:\"only_right,omitempty\"`" + `
+	Scores []float64 ` + "`json:\"scores\"`" + `
+}`,
+			wantConflicts: []string{
+				"ID: type int and float64 are resolved to float64",
+				`Name: tag "json:\"name\"" and "json:\"full_name\"" are resolved to "json:\"name\""`,
+				"Note: type interface {} and string are resolved to *string",
+				"Scores: type []int and []float64 are resolved to []float64",
+			},
+		},
+		{
+			name: "right",
+			opts: MergeOptions{Policy: PolicyRight},
+			wantDefinition: `type Left struct {
+	ID float64 ` + "`json:\"id\"`" + `
+	Item struct {
+		Count int ` + "`json:\"count\"`" + `
+		Key string ` + "`json:\"key\"`" + `
+	} ` + "`json:\"item\"`" + `
+	Name string ` + "`json:\"full_name\"`" + `
+	Note string ` + "`json:\"note\"`" + `
+	OnlyLeft bool ` + "`json:\"only_left\"`" + `
+	OnlyRight string ` + "`json:\"only_right\"`" + `
+	Scores []float64 ` + "`json:\"scores\"`" + `
+}`,
+			wantConflicts: []string{
+				"ID: type int and float64 are resolved to float64",
+				`Name: tag "json:\"name\"" and "json:\"full_name\"" are resolved to "json:\"full_name\""`,
+				"Note: type interface {} and string are resolved to string",
+				"Scores: type []int and []float64 are resolved to []float64",
+			},
+		},
+		{
+			name: "interface",
+			opts: MergeOptions{Policy: PolicyInterface},
+			wantDefinition: `type Left struct {
+	ID interface {} ` + "`json:\"id\"`" + `
+	Item struct {
+		Count int ` + "`json:\"count\"`" + `
+		Key string ` + "`json:\"key\"`" + `
+	} ` + "`json:\"item\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+	Note interface {} ` + "`json:\"note\"`" + `
+	OnlyLeft bool ` + "`json:\"only_left\"`" + `
+	OnlyRight string ` + "`json:\"only_right\"`" + `
+	Scores interface {} ` + "`json:\"scores\"`" + `
+}`,
+			wantConflicts: []string{
+				"ID: type int and float64 are resolved to interface {}",
+				`Name: tag "json:\"name\"" and "json:\"full_name\"" are resolved to "json:\"name\""`,
+				"Note: type interface {} and string are resolved to interface {}",
+				"Scores: type []int and []float64 are resolved to interface {}",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			a, b := newMergeTestStructs(t)
+			ds, conflicts, err := Merge(a, b, tt.opts)
+			if err != nil {
+				if !tt.wantError {
+					t.Fatalf("unexpected error is returned from Merge: %v", err)
+				}
+				return
+			} else if tt.wantError {
+				t.Fatalf("error is expected but it does not occur from Merge")
+			}
+
+			if ds.Name() != "Left" || !ds.IsPtr() {
+				t.Errorf("unexpected name or pointer mode. got: %s, %v", ds.Name(), ds.IsPtr())
+			}
+
+			if d := cmp.Diff(ds.Definition(), tt.wantDefinition); d != "" {
+				t.Fatalf("mismatch Definition: (-got +want)\n%s", d)
+			}
+
+			if d := cmp.Diff(conflictStrings(conflicts), tt.wantConflicts); d != "" {
+				t.Fatalf("mismatch conflicts: (-got +want)\n%s", d)
+			}
+
+			// result can be used as a new interface
+			_ = ds.NewInterface()
+		})
+	}
+}
+
+func TestMergeError(t *testing.T) {
+	t.Parallel()
+
+	a, _ := NewBuilder().AddString("F").Build()
+	b, _ := NewBuilder().AddBool("F").Build()
+
+	if _, _, err := Merge(a, b, MergeOptions{Policy: PolicyError}); err == nil {
+		t.Fatalf("error is expected but it does not occur from Merge")
+	}
+
+	ds, conflicts, err := Merge(a, b, MergeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error is returned from Merge: %v", err)
+	}
+	if f, _ := ds.FieldByName("F"); f.Type.Kind() != reflect.Interface {
+		t.Errorf("unexpected type of F. got: %v, want: interface {}", f.Type)
+	}
+	if len(conflicts) != 1 {
+		t.Errorf("unexpected conflicts. got: %v", conflicts)
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	t.Parallel()
+
+	a, b := newMergeTestStructs(t)
+	ds, conflicts, err := Intersect(a, b, MergeOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error is returned from Intersect: %v", err)
+	}
+
+	want := `type Left struct {
+	ID float64 ` + "`json:\"id\"`" + `
+	Item struct {
+		Key string ` + "`json:\"key\"`" + `
+	} ` + "`json:\"item\"`" + `
+	Name string ` + "`json:\"name\"`" + `
+	Note *string ` + "`json:\"note\"`" + `
+	Scores []float64 ` + "`json:\"scores\"`" + `
+}`
+	if d := cmp.Diff(ds.Definition(), want); d != "" {
+		t.Fatalf("mismatch Definition: (-got +want)\n%s", d)
+	}
+	if len(conflicts) != 4 {
+		t.Errorf("unexpected conflicts. got: %v", conflicts)
+	}
+}
+
+func TestSubtract(t *testing.T) {
+	t.Parallel()
+
+	a, b := newMergeTestStructs(t)
+	ds, err := Subtract(a, b)
+	if err != nil {
+		t.Fatalf("unexpected error is returned from Subtract: %v", err)
+	}
+
+	want := `type Left struct {
+	OnlyLeft bool ` + "`json:\"only_left\"`" + `
+}`
+	if d := cmp.Diff(ds.Definition(), want); d != "" {
+		t.Fatalf("mismatch Definition: (-got +want)\n%s", d)
+	}
+}
+
+func conflictStrings(conflicts []Conflict) []string {
+	ss := make([]string, len(conflicts))
+	for i, c := range conflicts {
+		ss[i] = c.String()
+	}
+	return ss
+}
+
+func TestMergeNumbers(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		a    interface{}
+		b    interface{}
+		want reflect.Type
+	}{
+		{name: "IntAndInt32", a: int(0), b: int32(0), want: reflect.TypeOf(int64(0))},
+		{name: "IntAndUint32", a: int(0), b: uint32(0), want: reflect.TypeOf(int64(0))},
+		{name: "Uint8AndUint64", a: uint8(0), b: uint64(0), want: reflect.TypeOf(uint64(0))},
+		{name: "Int64AndUint64", a: int64(0), b: uint64(0), want: reflect.TypeOf(float64(0))},
+		{name: "UintAndInt8", a: uint(0), b: int8(0), want: reflect.TypeOf(float64(0))},
+		{name: "Int64AndFloat32", a: int64(0), b: float32(0), want: reflect.TypeOf(float64(0))},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			a, err := NewBuilder().AddType("N", reflect.TypeOf(tt.a)).Build()
+			if err != nil {
+				t.Fatalf("unexpected error caused by Build: %v", err)
+			}
+			b, err := NewBuilder().AddType("N", reflect.TypeOf(tt.b)).Build()
+			if err != nil {
+				t.Fatalf("unexpected error caused by Build: %v", err)
+			}
+
+			ds, _, err := Merge(a, b, MergeOptions{Policy: PolicyError})
+			if err != nil {
+				t.Fatalf("unexpected error is returned from Merge: %v", err)
+			}
+			if f, _ := ds.FieldByName("N"); f.Type != tt.want {
+				t.Errorf("unexpected type of N. got: %v, want: %v", f.Type, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeMetasAndRefs(t *testing.T) {
+	t.Parallel()
+
+	a, err := newMetaTestBuilder().
+		AddRefSliceWithTag("Children", NewRef("Form"), `json:"children"`).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+	b, err := NewBuilder().
+		AddStringWithTag("Name", `json:"name"`).
+		AddStringWithTag("Email", `json:"email"`).
+		SetMeta("Name", FieldMeta{Description: "The other name"}).
+		SetMeta("Email", FieldMeta{Description: "The email"}).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		fn        func() (*DynamicStruct, error)
+		wantMetas []string
+		wantRef   bool
+		wantJSON  string
+	}{
+		{
+			name: "Merge",
+			fn: func() (*DynamicStruct, error) {
+				ds, _, err := Merge(a, b, MergeOptions{})
+				return ds, err
+			},
+			wantMetas: []string{"Age", "Email", "Name", "Obj.Count"},
+			wantRef:   true,
+			wantJSON:  `{"name":"anonymous","age":0,"obj":{"key":"","count":3},"children":null,"email":""}`,
+		},
+		{
+			name: "Intersect",
+			fn: func() (*DynamicStruct, error) {
+				ds, _, err := Intersect(a, b, MergeOptions{})
+				return ds, err
+			},
+			wantMetas: []string{"Name"},
+			wantJSON:  `{"name":"anonymous"}`,
+		},
+		{
+			name: "Subtract",
+			fn: func() (*DynamicStruct, error) {
+				return Subtract(a, b)
+			},
+			wantMetas: []string{"Age", "Obj.Count"},
+			wantRef:   true,
+			wantJSON:  `{"age":0,"obj":{"key":"","count":3},"children":null}`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ds, err := tt.fn()
+			if err != nil {
+				t.Fatalf("unexpected error is returned: %v", err)
+			}
+
+			var paths []string
+			for p := range ds.Metas() {
+				paths = append(paths, p)
+			}
+			sort.Strings(paths)
+			if d := cmp.Diff(paths, tt.wantMetas); d != "" {
+				t.Errorf("mismatch Metas: (-got +want)\n%s", d)
+			}
+			// metadata of a take precedence
+			if m, ok := ds.Meta("Name"); ok && m.Description != "The name" {
+				t.Errorf("unexpected Meta(Name): %+v", m)
+			}
+
+			r, ok := ds.Ref("Children")
+			if ok != tt.wantRef {
+				t.Fatalf("unexpected Ref(Children). got: %v, want: %v", ok, tt.wantRef)
+			}
+			if ok && r.DynamicStruct() != ds {
+				t.Errorf("self Ref should be resolved to the result DynamicStruct")
+			}
+
+			// defaults are set by NewInterface
+			got, err := json.Marshal(ds.NewInterface())
+			if err != nil {
+				t.Fatalf("unexpected error caused by json.Marshal: %v", err)
+			}
+			if d := cmp.Diff(string(got), tt.wantJSON); d != "" {
+				t.Errorf("mismatch NewInterface: (-got +want)\n%s", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package dynamicstruct
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// FieldMeta is the metadata of a field.
+// FieldMeta is rendered as comments by Definition and GoSource, and exported by JSONSchema and Schema.
+type FieldMeta struct {
+	// Description is the human readable description of the field.
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+
+	// Default is the default value of the field.
+	// NewInterface and New set it to the field. It must be assignable to the field type (see Instance.Set).
+	Default interface{} `json:"default,omitempty" yaml:"default,omitempty"`
+
+	// Example is an example value of the field.
+	Example interface{} `json:"example,omitempty" yaml:"example,omitempty"`
+
+	// Deprecated reports whether the field is deprecated.
+	Deprecated bool `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+
+	// Minimum and Maximum are the inclusive range of number values.
+	Minimum *float64 `json:"minimum,omitempty" yaml:"minimum,omitempty"`
+	Maximum *float64 `json:"maximum,omitempty" yaml:"maximum,omitempty"`
+
+	// MinLength and MaxLength are the range of string lengths.
+	MinLength *int `json:"minLength,omitempty" yaml:"minLength,omitempty"`
+	MaxLength *int `json:"maxLength,omitempty" yaml:"maxLength,omitempty"`
+
+	// Pattern is the regular expression that string values must match.
+	Pattern string `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+
+	// Enum is the allowed values.
+	Enum []interface{} `json:"enum,omitempty" yaml:"enum,omitempty"`
+}
+
+// IsZero reports whether m has no metadata.
+func (m FieldMeta) IsZero() bool {
+	return reflect.ValueOf(m).IsZero()
+}
+
+// comment returns the one line summary of m.
+func (m FieldMeta) comment() string {
+	var parts []string
+	if m.Description != "" {
+		parts = append(parts, strings.Join(strings.Fields(m.Description), " "))
+	}
+	if m.Deprecated {
+		parts = append(parts, "deprecated")
+	}
+	if m.Default != nil {
+		parts = append(parts, fmt.Sprintf("default: %v", m.Default))
+	}
+	if m.Example != nil {
+		parts = append(parts, fmt.Sprintf("example: %v", m.Example))
+	}
+	if m.Minimum != nil {
+		parts = append(parts, fmt.Sprintf("minimum: %v", *m.Minimum))
+	}
+	if m.Maximum != nil {
+		parts = append(parts, fmt.Sprintf("maximum: %v", *m.Maximum))
+	}
+	if m.MinLength != nil {
+		parts = append(parts, fmt.Sprintf("minLength: %d", *m.MinLength))
+	}
+	if m.MaxLength != nil {
+		parts = append(parts, fmt.Sprintf("maxLength: %d", *m.MaxLength))
+	}
+	if m.Pattern != "" {
+		parts = append(parts, fmt.Sprintf("pattern: %s", m.Pattern))
+	}
+	if len(m.Enum) > 0 {
+		parts = append(parts, fmt.Sprintf("enum: %v", m.Enum))
+	}
+
+	return strings.Join(parts, "; ")
+}
+
+// SetMeta returns a Builder that was set the metadata for the specific field.
+// path is the field name, or the dot separated path to a field of a nested struct (e.g. "Obj.Key").
+// If the field does not exist or Default or Example can not be set to the field, the error is returned by Build.
+func (b *Builder) SetMeta(path string, meta FieldMeta) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	name, rest, nested := strings.Cut(path, ".")
+	bf := b.getFieldMap(name)
+	if bf == nil {
+		b.setErr(fmt.Errorf("field %s does not exist", path))
+		return b
+	}
+
+	typ := bf.typ
+	if nested {
+		var ok bool
+		if typ, ok = fieldTypeByPath(typ, rest); !ok {
+			b.setErr(fmt.Errorf("field %s does not exist", path))
+			return b
+		}
+	}
+
+	for _, v := range []interface{}{meta.Default, meta.Example} {
+		if v == nil {
+			continue
+		}
+		if err := assignValue(reflect.New(typ).Elem(), v, path); err != nil {
+			b.setErr(fmt.Errorf("invalid metadata: %w", err))
+			return b
+		}
+	}
+
+	if !nested {
+		bf.meta = meta
+		return b
+	}
+
+	if bf.nestedMetas == nil {
+		bf.nestedMetas = make(map[string]FieldMeta)
+	}
+	bf.nestedMetas[rest] = meta
+
+	return b
+}
+
+// GetMeta returns the metadata of the specific field and a boolean indicating if the metadata was set.
+// path is the field name, or the dot separated path to a field of a nested struct (e.g. "Obj.Key").
+func (b *Builder) GetMeta(path string) (FieldMeta, bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	name, rest, nested := strings.Cut(path, ".")
+	bf := b.getFieldMap(name)
+	if bf == nil {
+		return FieldMeta{}, false
+	}
+
+	if !nested {
+		return bf.meta, !bf.meta.IsZero()
+	}
+
+	m, ok := bf.nestedMetas[rest]
+	return m, ok
+}
+
+// setErr must be called with b.mu held.
+func (b *Builder) setErr(err error) {
+	// keep 1st error
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+// Meta returns the metadata of the specific field and a boolean indicating if the metadata was set.
+// path is the field name, or the dot separated path to a field of a nested struct (e.g. "Obj.Key").
+func (ds *DynamicStruct) Meta(path string) (FieldMeta, bool) {
+	m, ok := ds.metas[path]
+	return m, ok
+}
+
+// Metas returns the all metadata of fields keyed by field paths.
+func (ds *DynamicStruct) Metas() map[string]FieldMeta {
+	metas := make(map[string]FieldMeta, len(ds.metas))
+	for p, m := range ds.metas {
+		metas[p] = m
+	}
+	return metas
+}
+
+// subMetas returns the metadata under prefix with the paths relative to prefix.
+func subMetas(metas map[string]FieldMeta, prefix string) map[string]FieldMeta {
+	var sub map[string]FieldMeta
+	for p, m := range metas {
+		if rest := strings.TrimPrefix(p, prefix+"."); rest != p {
+			if sub == nil {
+				sub = make(map[string]FieldMeta)
+			}
+			sub[rest] = m
+		}
+	}
+	return sub
+}
+
+// fieldTypeByPath returns the type of the nested field by the dot separated path.
+// Pointers, slices, arrays and maps are traversed to their element types.
+func fieldTypeByPath(t reflect.Type, path string) (reflect.Type, bool) {
+	for _, name := range strings.Split(path, ".") {
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return nil, false
+		}
+
+		sf, ok := t.FieldByName(name)
+		if !ok {
+			return nil, false
+		}
+		t = sf.Type
+	}
+
+	return t, true
+}
+
+// applyDefaults sets the default values in metas to the fields of the struct value sv.
+// Fields under nil pointers, slices and maps are skipped.
+func applyDefaults(sv reflect.Value, metas map[string]FieldMeta) {
+	paths := make([]string, 0, len(metas))
+	for p, m := range metas {
+		if m.Default != nil {
+			paths = append(paths, p)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, p := range paths {
+		if fv, ok := fieldValueByPath(sv, p); ok {
+			// the default value was validated by SetMeta
+			_ = assignValue(fv, metas[p].Default, p)
+		}
+	}
+}
+
+func fieldValueByPath(sv reflect.Value, path string) (reflect.Value, bool) {
+	cur := sv
+	for _, name := range strings.Split(path, ".") {
+		if cur.Kind() == reflect.Ptr {
+			if cur.IsNil() {
+				return reflect.Value{}, false
+			}
+			cur = cur.Elem()
+		}
+		if cur.Kind() != reflect.Struct {
+			return reflect.Value{}, false
+		}
+
+		cur = cur.FieldByName(name)
+		if !cur.IsValid() {
+			return reflect.Value{}, false
+		}
+	}
+
+	return cur, true
+}
@@ -0,0 +1,223 @@
+package dynamicstruct_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	. "github.com/goldeneggg/structil/dynamicstruct"
+)
+
+func float64Ptr(f float64) *float64 {
+	return &f
+}
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func newMetaTestBuilder() *Builder {
+	nds, _ := NewBuilder().
+		AddStringWithTag("Key", `json:"key"`).
+		AddIntWithTag("Count", `json:"count"`).
+		BuildNonPtr()
+
+	return NewBuilder().
+		SetStructName("Form").
+		AddStringWithTag("Name", `json:"name"`).
+		AddIntWithTag("Age", `json:"age"`).
+		AddDynamicStructWithTag("Obj", nds, false, `json:"obj"`).
+		SetMeta("Name", FieldMeta{Description: "The name", Default: "anonymous", MaxLength: intPtr(10)}).
+		SetMeta("Age", FieldMeta{Deprecated: true, Minimum: float64Ptr(0), Example: 20}).
+		SetMeta("Obj.Count", FieldMeta{Default: 3})
+}
+
+func TestSetMeta(t *testing.T) {
+	t.Parallel()
+
+	ds, err := newMetaTestBuilder().Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	wantDef := `type Form struct {
+	Age int ` + "`json:\"age\"`" + ` // deprecated; example: 20; minimum: 0
+	Name string ` + "`json:\"name\"`" + ` // The name; default: anonymous; maxLength: 10
+	Obj struct {
+		Count int ` + "`json:\"count\"`" + ` // default: 3
+		Key string ` + "`json:\"key\"`" + `
+	} ` + "`json:\"obj\"`" + `
+}`
+	if d := cmp.Diff(ds.Definition(), wantDef); d != "" {
+		t.Errorf("mismatch Definition: (-got +want)\n%s", d)
+	}
+
+	if m, ok := ds.Meta("Obj.Count"); !ok || m.Default != 3 {
+		t.Errorf("unexpected Meta(Obj.Count): %+v, %v", m, ok)
+	}
+	if _, ok := ds.Meta("Obj.Key"); ok {
+		t.Errorf("Meta(Obj.Key) should not exist")
+	}
+
+	// defaults are set by NewInterface
+	b, err := json.Marshal(ds.NewInterface())
+	if err != nil {
+		t.Fatalf("unexpected error caused by json.Marshal: %v", err)
+	}
+	wantJSON := `{"name":"anonymous","age":0,"obj":{"key":"","count":3}}`
+	if d := cmp.Diff(string(b), wantJSON); d != "" {
+		t.Errorf("mismatch NewInterface: (-got +want)\n%s", d)
+	}
+
+	// metadata are kept by ToBuilder
+	b2 := ds.ToBuilder()
+	if m, ok := b2.GetMeta("Name"); !ok || m.Description != "The name" {
+		t.Errorf("unexpected GetMeta(Name): %+v, %v", m, ok)
+	}
+	if m, ok := b2.GetMeta("Obj.Count"); !ok || m.Default != 3 {
+		t.Errorf("unexpected GetMeta(Obj.Count): %+v, %v", m, ok)
+	}
+	ds2, err := b2.Remove("Age").Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+	if d := cmp.Diff(len(ds2.Metas()), 2); d != "" {
+		t.Errorf("mismatch number of Metas: (-got +want)\n%s", d)
+	}
+}
+
+func TestSetMetaError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		path    string
+		meta    FieldMeta
+		wantErr string
+	}{
+		{
+			name:    "unknown field",
+			path:    "Unknown",
+			meta:    FieldMeta{Description: "x"},
+			wantErr: "field Unknown does not exist",
+		},
+		{
+			name:    "unknown nested field",
+			path:    "Obj.Unknown",
+			meta:    FieldMeta{Description: "x"},
+			wantErr: "field Obj.Unknown does not exist",
+		},
+		{
+			name:    "nested field of non-struct",
+			path:    "Name.Key",
+			meta:    FieldMeta{Description: "x"},
+			wantErr: "field Name.Key does not exist",
+		},
+		{
+			name:    "default of wrong type",
+			path:    "Age",
+			meta:    FieldMeta{Default: "twenty"},
+			wantErr: "invalid metadata",
+		},
+		{
+			name:    "example overflows",
+			path:    "Obj.Count",
+			meta:    FieldMeta{Example: 1.5},
+			wantErr: "invalid metadata",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := newMetaTestBuilder().SetMeta(tt.path, tt.meta).Build()
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMetaSchemas(t *testing.T) {
+	t.Parallel()
+
+	ds, err := newMetaTestBuilder().Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	t.Run("JSONSchema", func(t *testing.T) {
+		t.Parallel()
+
+		js, err := ds.JSONSchema()
+		if err != nil {
+			t.Fatalf("unexpected error caused by JSONSchema: %v", err)
+		}
+		for _, want := range []string{
+			`"description": "The name"`,
+			`"default": "anonymous"`,
+			`"maxLength": 10`,
+			`"deprecated": true`,
+			`"examples": [`,
+			`"minimum": 0`,
+			`"default": 3`,
+		} {
+			if !strings.Contains(string(js), want) {
+				t.Errorf("JSONSchema does not contain %s\n%s", want, js)
+			}
+		}
+
+		fds, err := FromJSONSchema(js)
+		if err != nil {
+			t.Fatalf("unexpected error caused by FromJSONSchema: %v", err)
+		}
+		m, ok := fds.Meta("Name")
+		if !ok {
+			t.Fatalf("Meta(Name) should exist")
+		}
+		want := FieldMeta{Description: "The name", Default: "anonymous", MaxLength: intPtr(10)}
+		if d := cmp.Diff(m, want); d != "" {
+			t.Errorf("mismatch Meta(Name): (-got +want)\n%s", d)
+		}
+		if m, ok := fds.Meta("Obj.Count"); !ok || m.Default != float64(3) {
+			t.Errorf("unexpected Meta(Obj.Count): %+v, %v", m, ok)
+		}
+	})
+
+	t.Run("Schema", func(t *testing.T) {
+		t.Parallel()
+
+		data, err := ds.MarshalSchema()
+		if err != nil {
+			t.Fatalf("unexpected error caused by MarshalSchema: %v", err)
+		}
+		sds, err := UnmarshalSchema(data)
+		if err != nil {
+			t.Fatalf("unexpected error caused by UnmarshalSchema: %v", err)
+		}
+		if d := cmp.Diff(sds.Definition(), ds.Definition()); d != "" {
+			t.Errorf("mismatch Definition: (-got +want)\n%s", d)
+		}
+	})
+
+	t.Run("GoSource", func(t *testing.T) {
+		t.Parallel()
+
+		src, err := ds.GoSource(GoSourceOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error caused by GoSource: %v", err)
+		}
+		for _, want := range []string{
+			"\t// The name\n",
+			"\t// Deprecated: this field is deprecated.\n",
+		} {
+			if !strings.Contains(string(src), want) {
+				t.Errorf("GoSource does not contain %q\n%s", want, src)
+			}
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package dynamicstruct
+
+import (
+	"strings"
+	"unicode"
+)
+
+// commonInitialisms is the list of initialisms used by golint.
+// See: https://github.com/golang/go/wiki/CodeReviewComments#initialisms
+var commonInitialisms = map[string]bool{
+	"ACL":   true,
+	"API":   true,
+	"ASCII": true,
+	"CPU":   true,
+	"CSS":   true,
+	"DNS":   true,
+	"EOF":   true,
+	"GUID":  true,
+	"HTML":  true,
+	"HTTP":  true,
+	"HTTPS": true,
+	"ID":    true,
+	"IP":    true,
+	"JSON":  true,
+	"LHS":   true,
+	"QPS":   true,
+	"RAM":   true,
+	"RHS":   true,
+	"RPC":   true,
+	"SLA":   true,
+	"SMTP":  true,
+	"SQL":   true,
+	"SSH":   true,
+	"TCP":   true,
+	"TLS":   true,
+	"TTL":   true,
+	"UDP":   true,
+	"UI":    true,
+	"UID":   true,
+	"UUID":  true,
+	"URI":   true,
+	"URL":   true,
+	"UTF8":  true,
+	"VM":    true,
+	"XML":   true,
+	"XMPP":  true,
+	"XSRF":  true,
+	"XSS":   true,
+}
+
+// ExportedName converts s to an exported Go identifier that follows the Go naming conventions.
+// Common initialisms are upper-cased (e.g. "user_id" to "UserID" and "api_url" to "APIURL"),
+// characters that can not be used in identifiers are treated as separators (e.g. "@type" to "Type"),
+// "F" is prefixed if the name begins with a digit (e.g. "2fa" to "F2fa")
+// and "X" is prefixed if the name begins with a letter that has no case (e.g. "かな" to "Xかな").
+// This returns an empty string if s has no letters and digits.
+func ExportedName(s string) string {
+	var sb strings.Builder
+	for _, w := range splitWords(s) {
+		if u := strings.ToUpper(w); commonInitialisms[u] {
+			sb.WriteString(u)
+			continue
+		}
+		rs := []rune(strings.ToLower(w))
+		rs[0] = unicode.ToUpper(rs[0])
+		sb.WriteString(string(rs))
+	}
+
+	name := sb.String()
+	if name == "" {
+		return ""
+	}
+	if r := []rune(name)[0]; unicode.IsDigit(r) {
+		name = "F" + name
+	} else if !unicode.IsUpper(r) {
+		name = "X" + name
+	}
+
+	return name
+}
+
+// splitWords splits s into words by the characters other than letters and digits,
+// and by the camel case boundaries (e.g. "userID" to "user" and "ID", "HTTPServer" to "HTTP" and "Server").
+func splitWords(s string) []string {
+	var words []string
+	rs := []rune(s)
+	start := -1
+	for i, r := range rs {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if start >= 0 {
+				words = append(words, string(rs[start:i]))
+				start = -1
+			}
+			continue
+		}
+
+		if start >= 0 && unicode.IsUpper(r) {
+			prev := rs[i-1]
+			nextLower := i+1 < len(rs) && unicode.IsLower(rs[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				words = append(words, string(rs[start:i]))
+				start = i
+			}
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, string(rs[start:]))
+	}
+
+	return words
+}
@@ -0,0 +1,41 @@
+package dynamicstruct_test
+
+import (
+	"testing"
+
+	. "github.com/goldeneggg/structil/dynamicstruct"
+)
+
+func TestExportedName(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{s: "name", want: "Name"},
+		{s: "user_id", want: "UserID"},
+		{s: "userId", want: "UserID"},
+		{s: "api_url", want: "APIURL"},
+		{s: "HTTPServer", want: "HTTPServer"},
+		{s: "utf8_text", want: "UTF8Text"},
+		{s: "ids", want: "Ids"},
+		{s: "@type", want: "Type"},
+		{s: "content/type", want: "ContentType"},
+		{s: "x-request-id", want: "XRequestID"},
+		{s: "2fa_enabled", want: "F2faEnabled"},
+		{s: "かな", want: "Xかな"},
+		{s: "@", want: ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.s, func(t *testing.T) {
+			t.Parallel()
+
+			if got := ExportedName(tt.s); got != tt.want {
+				t.Errorf("unexpected name. got: %s, want: %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package dynamicstruct
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var interfaceSliceType = reflect.SliceOf(interfaceType)
+
+// Ref is a reference to a DynamicStruct for recursive and mutually referencing types (e.g. a tree of nodes).
+//
+// reflect.StructOf can not express recursive types, so a field that refers to a Ref is built as interface{}
+// (or []interface{} for AddRefSlice) and the Ref is attached to the field as the schema of its values.
+// Decoded values of the field are map[string]interface{} (or []interface{} of them).
+//
+// A Ref whose name is the struct name of a Builder refers to the DynamicStruct built by the Builder (self reference),
+// and DynamicStruct.Ref returns a resolved copy of it. Other Refs must be resolved by Resolve
+// after the referred DynamicStruct is built.
+type Ref struct {
+	name string
+	ds   *DynamicStruct
+}
+
+// NewRef returns a new unresolved Ref to the struct with the given name.
+func NewRef(name string) *Ref {
+	return &Ref{name: name}
+}
+
+// Name returns the name of the referred struct.
+func (r *Ref) Name() string {
+	return r.name
+}
+
+// Resolve sets the referred DynamicStruct.
+func (r *Ref) Resolve(ds *DynamicStruct) *Ref {
+	r.ds = ds
+	return r
+}
+
+// DynamicStruct returns the referred DynamicStruct, or nil if this is not resolved.
+func (r *Ref) DynamicStruct() *DynamicStruct {
+	return r.ds
+}
+
+// AddRef returns a Builder that was added a field referring to r.
+// The field type is interface{}.
+func (b *Builder) AddRef(name string, r *Ref) *Builder {
+	return b.AddRefWithTag(name, r, "")
+}
+
+// AddRefWithTag returns a Builder that was added a field referring to r with tag.
+func (b *Builder) AddRefWithTag(name string, r *Ref, tag string) *Builder {
+	return b.addRef(name, r, interfaceType, tag)
+}
+
+// AddRefSlice returns a Builder that was added a field referring to the slice of r.
+// The field type is []interface{}.
+func (b *Builder) AddRefSlice(name string, r *Ref) *Builder {
+	return b.AddRefSliceWithTag(name, r, "")
+}
+
+// AddRefSliceWithTag returns a Builder that was added a field referring to the slice of r with tag.
+func (b *Builder) AddRefSliceWithTag(name string, r *Ref, tag string) *Builder {
+	return b.addRef(name, r, interfaceSliceType, tag)
+}
+
+func (b *Builder) addRef(name string, r *Ref, typ reflect.Type, tag string) *Builder {
+	if r == nil {
+		b.mu.Lock()
+		b.setErr(fmt.Errorf("field %s: ref must not be nil", name))
+		b.mu.Unlock()
+		return b
+	}
+
+	f := func() reflect.Type {
+		return typ
+	}
+	initField := func(bf *builderField) {
+		bf.ref = r
+	}
+	return b.addFieldFuncWith(name, false, tag, f, initField)
+}
+
+// setRef attaches r to the interface{} or []interface{} field by the dot separated path.
+func (b *Builder) setRef(path string, r *Ref) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	name, rest, nested := strings.Cut(path, ".")
+	bf := b.getFieldMap(name)
+	if bf == nil {
+		b.setErr(fmt.Errorf("field %s does not exist", path))
+		return
+	}
+
+	typ := bf.typ
+	if nested {
+		var ok bool
+		if typ, ok = fieldTypeByPath(typ, rest); !ok {
+			b.setErr(fmt.Errorf("field %s does not exist", path))
+			return
+		}
+	}
+	if typ != interfaceType && typ != interfaceSliceType {
+		b.setErr(fmt.Errorf("field %s: type %v can not refer to %s", path, typ, r.name))
+		return
+	}
+
+	if !nested {
+		bf.ref = r
+		return
+	}
+
+	if bf.nestedRefs == nil {
+		bf.nestedRefs = make(map[string]*Ref)
+	}
+	bf.nestedRefs[rest] = r
+}
+
+// Ref returns the Ref attached to the specific field and a boolean indicating if the field refers to a Ref.
+// path is the field name, or the dot separated path to a field of a nested struct (e.g. "Obj.Children").
+func (ds *DynamicStruct) Ref(path string) (*Ref, bool) {
+	r, ok := ds.refs[path]
+	return r, ok
+}
+
+// Refs returns the all Refs attached to fields keyed by field paths.
+func (ds *DynamicStruct) Refs() map[string]*Ref {
+	refs := make(map[string]*Ref, len(ds.refs))
+	for p, r := range ds.refs {
+		refs[p] = r
+	}
+	return refs
+}
+
+// subRefs returns the Refs under prefix with the paths relative to prefix.
+func subRefs(refs map[string]*Ref, prefix string) map[string]*Ref {
+	var sub map[string]*Ref
+	for p, r := range refs {
+		if rest := strings.TrimPrefix(p, prefix+"."); rest != p {
+			if sub == nil {
+				sub = make(map[string]*Ref)
+			}
+			sub[rest] = r
+		}
+	}
+	return sub
+}
@@ -0,0 +1,211 @@
+package dynamicstruct_test
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	. "github.com/goldeneggg/structil/dynamicstruct"
+)
+
+func buildRefTestNode(t *testing.T) *DynamicStruct {
+	t.Helper()
+
+	self := NewRef("Node")
+	ds, err := NewBuilder().
+		SetStructName("Node").
+		AddStringWithTag("Name", `json:"name"`).
+		AddRefWithTag("Parent", self, `json:"parent,omitempty"`).
+		AddRefSliceWithTag("Children", self, `json:"children"`).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	return ds
+}
+
+func TestRefSelf(t *testing.T) {
+	t.Parallel()
+
+	ds := buildRefTestNode(t)
+
+	r, ok := ds.Ref("Children")
+	if !ok {
+		t.Fatalf("Ref(Children) should exist")
+	}
+	if r.DynamicStruct() != ds {
+		t.Errorf("self Ref should be resolved to the built DynamicStruct")
+	}
+
+	wantDef := `type Node struct {
+	Children []interface {} ` + "`json:\"children\"`" + ` // ref: Node
+	Name string ` + "`json:\"name\"`" + `
+	Parent interface {} ` + "`json:\"parent,omitempty\"`" + ` // ref: Node
+}`
+	if d := cmp.Diff(ds.Definition(), wantDef); d != "" {
+		t.Errorf("mismatch Definition: (-got +want)\n%s", d)
+	}
+
+	// tree-shaped data is decoded without truncation
+	// (keys of the decoded maps are sorted by json.Marshal)
+	data := `{"name":"root","children":[{"children":[{"children":[],"name":"a1"}],"name":"a"}]}`
+	intf := ds.NewInterface()
+	if err := json.Unmarshal([]byte(data), intf); err != nil {
+		t.Fatalf("unexpected error caused by json.Unmarshal: %v", err)
+	}
+	b, err := json.Marshal(intf)
+	if err != nil {
+		t.Fatalf("unexpected error caused by json.Marshal: %v", err)
+	}
+	if d := cmp.Diff(string(b), data); d != "" {
+		t.Errorf("mismatch round trip: (-got +want)\n%s", d)
+	}
+
+	src, err := ds.GoSource(GoSourceOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error caused by GoSource: %v", err)
+	}
+	for _, want := range []string{"Children []*Node `json:\"children\"`", "Parent   *Node   `json:\"parent,omitempty\"`"} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("GoSource does not contain %q\n%s", want, src)
+		}
+	}
+
+	js, err := ds.JSONSchema()
+	if err != nil {
+		t.Fatalf("unexpected error caused by JSONSchema: %v", err)
+	}
+	fds, err := FromJSONSchema(js)
+	if err != nil {
+		t.Fatalf("unexpected error caused by FromJSONSchema: %v", err)
+	}
+	if d := cmp.Diff(fds.Definition(), wantDef); d != "" {
+		t.Errorf("mismatch Definition from JSONSchema: (-got +want)\n%s", d)
+	}
+	if r, ok := fds.Ref("Parent"); !ok || r.DynamicStruct() != fds {
+		t.Errorf("Ref(Parent) should be resolved to the root: %v", ok)
+	}
+
+	sds, err := UnmarshalSchema(mustMarshalSchema(t, ds))
+	if err != nil {
+		t.Fatalf("unexpected error caused by UnmarshalSchema: %v", err)
+	}
+	if d := cmp.Diff(sds.Definition(), wantDef); d != "" {
+		t.Errorf("mismatch Definition from Schema: (-got +want)\n%s", d)
+	}
+	if r, ok := sds.Ref("Children"); !ok || r.DynamicStruct() != sds {
+		t.Errorf("Ref(Children) should be resolved to the rebuilt DynamicStruct: %v", ok)
+	}
+}
+
+func mustMarshalSchema(t *testing.T, ds *DynamicStruct) []byte {
+	t.Helper()
+
+	data, err := ds.MarshalSchema()
+	if err != nil {
+		t.Fatalf("unexpected error caused by MarshalSchema: %v", err)
+	}
+	return data
+}
+
+func TestRefMutual(t *testing.T) {
+	t.Parallel()
+
+	deptRef := NewRef("Dept")
+	emp, err := NewBuilder().
+		SetStructName("Employee").
+		AddStringWithTag("Name", `json:"name"`).
+		AddRefWithTag("Dept", deptRef, `json:"dept"`).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	dept, err := NewBuilder().
+		SetStructName("Dept").
+		AddStringWithTag("Title", `json:"title"`).
+		AddDynamicStructSliceWithTag("Members", emp, `json:"members"`).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+	deptRef.Resolve(dept)
+
+	// Refs of nested DynamicStructs are kept
+	if r, ok := dept.Ref("Members.Dept"); !ok || r.DynamicStruct() != dept {
+		t.Errorf("Ref(Members.Dept) should be resolved to Dept: %v", ok)
+	}
+
+	src, err := emp.GoSource(GoSourceOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error caused by GoSource: %v", err)
+	}
+	for _, want := range []string{"type Employee struct", "type Dept struct", "Dept *Dept", "Members []*Employee"} {
+		if !strings.Contains(string(src), want) {
+			t.Errorf("GoSource does not contain %q\n%s", want, src)
+		}
+	}
+
+	js, err := emp.JSONSchema()
+	if err != nil {
+		t.Fatalf("unexpected error caused by JSONSchema: %v", err)
+	}
+	for _, want := range []string{`"$ref": "#/$defs/Dept"`, `"$ref": "#"`} {
+		if !strings.Contains(string(js), want) {
+			t.Errorf("JSONSchema does not contain %s\n%s", want, js)
+		}
+	}
+
+	// $defs references are resolved to the DynamicStructs built from them
+	fds, err := FromJSONSchema(js)
+	if err != nil {
+		t.Fatalf("unexpected error caused by FromJSONSchema: %v", err)
+	}
+	r, ok := fds.Ref("Dept.Members")
+	if !ok || r.DynamicStruct() != fds {
+		t.Fatalf("Ref(Dept.Members) should be resolved to the root: %v", ok)
+	}
+}
+
+func TestRefError(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewBuilder().AddRef("Parent", nil).Build(); err == nil || !strings.Contains(err.Error(), "ref must not be nil") {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	s := &Schema{
+		Version: SchemaVersion,
+		Name:    "Node",
+		Fields:  []SchemaField{{Name: "Name", Type: &SchemaType{Kind: "string"}}},
+		Refs:    map[string]string{"Name": "Node"},
+	}
+	if _, err := s.Build(); err == nil || !strings.Contains(err.Error(), "field Name: type string can not refer to Node") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestRefSelfRebuild(t *testing.T) {
+	t.Parallel()
+
+	b := NewBuilder().SetStructName("Node").AddRefSlice("Children", NewRef("Node"))
+	ds1, err := b.Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+	ds2, err := b.Clone().AddString("Name").Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	// each build refers to itself
+	if r, _ := ds1.Ref("Children"); r.DynamicStruct() != ds1 {
+		t.Errorf("Ref of ds1 is not resolved to ds1")
+	}
+	if r, _ := ds2.Ref("Children"); r.DynamicStruct() != ds2 {
+		t.Errorf("Ref of ds2 is not resolved to ds2")
+	}
+}
@@ -0,0 +1,344 @@
+package dynamicstruct
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is the current version of the serialised schema format.
+const SchemaVersion = 1
+
+// Schema is the serialisable description of a DynamicStruct.
+// A DynamicStruct rebuilt from a Schema has the identical reflect.Type as the original,
+// except that unregistered named struct types are rebuilt as unnamed struct types.
+type Schema struct {
+	Version int           `json:"version" yaml:"version"`
+	Name    string        `json:"name" yaml:"name"`
+	Ptr     bool          `json:"ptr" yaml:"ptr"`
+	Fields  []SchemaField `json:"fields" yaml:"fields"`
+
+	// Metas is the metadata of fields keyed by the field paths (e.g. "ObjField.Id"). See FieldMeta.
+	Metas map[string]FieldMeta `json:"metas,omitempty" yaml:"metas,omitempty"`
+
+	// Refs is the names of the structs referred by fields keyed by the field paths. See Ref.
+	// The Refs to the name of this are resolved to the rebuilt DynamicStruct, and the others are unresolved.
+	Refs map[string]string `json:"refs,omitempty" yaml:"refs,omitempty"`
+}
+
+// SchemaField is the description of a struct field.
+type SchemaField struct {
+	Name     string      `json:"name" yaml:"name"`
+	Type     *SchemaType `json:"type" yaml:"type"`
+	Tag      string      `json:"tag,omitempty" yaml:"tag,omitempty"`
+	Embedded bool        `json:"embedded,omitempty" yaml:"embedded,omitempty"`
+}
+
+// SchemaType is the description of a field type.
+//
+// Kind is the name of reflect.Kind (e.g. "int", "ptr", "slice", "struct").
+// Named is the registered name for named types (e.g. "time.Time"). See RegisterSchemaType.
+// Elem is the element type of "ptr", "slice", "array" and "map", Key is the key type of "map",
+// Len is the length of "array" and Fields are the fields of "struct".
+type SchemaType struct {
+	Kind   string        `json:"kind" yaml:"kind"`
+	Named  string        `json:"named,omitempty" yaml:"named,omitempty"`
+	Elem   *SchemaType   `json:"elem,omitempty" yaml:"elem,omitempty"`
+	Key    *SchemaType   `json:"key,omitempty" yaml:"key,omitempty"`
+	Len    int           `json:"len,omitempty" yaml:"len,omitempty"`
+	Fields []SchemaField `json:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
+var (
+	schemaKinds = map[string]reflect.Type{}
+
+	namedSchemaTypesMu sync.RWMutex
+	namedSchemaTypes   = map[string]reflect.Type{
+		"time.Time":       timeType,
+		"time.Duration":   reflect.TypeOf(time.Duration(0)),
+		"json.Number":     reflect.TypeOf(json.Number("")),
+		"json.RawMessage": reflect.TypeOf(json.RawMessage(nil)),
+	}
+)
+
+func init() {
+	for _, i := range []interface{}{
+		false, "",
+		int(0), int8(0), int16(0), int32(0), int64(0),
+		uint(0), uint8(0), uint16(0), uint32(0), uint64(0), uintptr(0),
+		float32(0), float64(0), complex64(0), complex128(0),
+	} {
+		t := reflect.TypeOf(i)
+		schemaKinds[t.Kind().String()] = t
+	}
+	schemaKinds[reflect.Interface.String()] = interfaceType
+}
+
+// RegisterSchemaType registers the named type t with name for the serialised schema.
+// Fields of the registered types are serialised with name instead of their structures.
+// time.Time, time.Duration, json.Number and json.RawMessage are registered by default.
+func RegisterSchemaType(name string, t reflect.Type) {
+	namedSchemaTypesMu.Lock()
+	defer namedSchemaTypesMu.Unlock()
+
+	namedSchemaTypes[name] = t
+}
+
+func lookupNamedSchemaType(name string) (reflect.Type, bool) {
+	namedSchemaTypesMu.RLock()
+	defer namedSchemaTypesMu.RUnlock()
+
+	t, ok := namedSchemaTypes[name]
+	return t, ok
+}
+
+func namedSchemaTypeName(t reflect.Type) (string, bool) {
+	namedSchemaTypesMu.RLock()
+	defer namedSchemaTypesMu.RUnlock()
+
+	for name, nt := range namedSchemaTypes {
+		if nt == t {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Schema returns the serialisable description of this.
+// An error is returned if this has a field of func, chan, non-empty interface or unregistered named type.
+func (ds *DynamicStruct) Schema() (*Schema, error) {
+	fields, err := toSchemaFields(ds.fields, "")
+	if err != nil {
+		return nil, err
+	}
+
+	var refs map[string]string
+	for p, r := range ds.refs {
+		if refs == nil {
+			refs = make(map[string]string, len(ds.refs))
+		}
+		refs[p] = r.name
+	}
+
+	return &Schema{
+		Version: SchemaVersion,
+		Name:    ds.name,
+		Ptr:     ds.isPtr,
+		Fields:  fields,
+		Metas:   ds.Metas(),
+		Refs:    refs,
+	}, nil
+}
+
+// MarshalSchema returns the JSON encoded schema of this. See Schema.
+func (ds *DynamicStruct) MarshalSchema() ([]byte, error) {
+	s, err := ds.Schema()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.MarshalIndent(s, "", "  ")
+}
+
+// MarshalSchemaYAML returns the YAML encoded schema of this. See Schema.
+func (ds *DynamicStruct) MarshalSchemaYAML() ([]byte, error) {
+	s, err := ds.Schema()
+	if err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(s)
+}
+
+// UnmarshalSchema returns a DynamicStruct built from the JSON or YAML encoded schema.
+func UnmarshalSchema(data []byte) (*DynamicStruct, error) {
+	var s Schema
+	var err error
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		err = json.Unmarshal(data, &s)
+	} else {
+		err = yaml.Unmarshal(data, &s)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fail to unmarshal schema: %w", err)
+	}
+
+	return s.Build()
+}
+
+// Build returns a DynamicStruct built from this.
+func (s *Schema) Build() (*DynamicStruct, error) {
+	if s.Version < 1 || s.Version > SchemaVersion {
+		return nil, fmt.Errorf("unsupported schema version %d", s.Version)
+	}
+
+	b := NewBuilder()
+	if s.Name != "" {
+		b.SetStructName(s.Name)
+	}
+	for _, f := range s.Fields {
+		typ, err := f.Type.reflectType(f.Name)
+		if err != nil {
+			return nil, err
+		}
+		b.AddTypeWithTag(f.Name, typ, f.Tag)
+	}
+
+	paths := make([]string, 0, len(s.Metas))
+	for path := range s.Metas {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		b.SetMeta(path, s.Metas[path])
+	}
+
+	refs := make(map[string]*Ref)
+	for path, name := range s.Refs {
+		if _, ok := refs[name]; !ok {
+			refs[name] = NewRef(name)
+		}
+		b.setRef(path, refs[name])
+	}
+
+	return b.build(s.Ptr)
+}
+
+func toSchemaFields(fields []reflect.StructField, path string) ([]SchemaField, error) {
+	sfs := make([]SchemaField, 0, len(fields))
+	for _, f := range fields {
+		p := joinPath(path, f.Name)
+		st, err := toSchemaType(f.Type, p)
+		if err != nil {
+			return nil, err
+		}
+
+		sfs = append(sfs, SchemaField{
+			Name:     f.Name,
+			Type:     st,
+			Tag:      string(f.Tag),
+			Embedded: f.Anonymous,
+		})
+	}
+
+	return sfs, nil
+}
+
+func toSchemaType(t reflect.Type, path string) (*SchemaType, error) {
+	if name, ok := namedSchemaTypeName(t); ok {
+		return &SchemaType{Kind: t.Kind().String(), Named: name}, nil
+	}
+
+	// named struct types that have only exported fields are described by their structures
+	k := t.Kind()
+	if t.Name() != "" && t.PkgPath() != "" && !(k == reflect.Struct && hasOnlyExportedFields(t)) {
+		return nil, fmt.Errorf("field %s: named type %v is not registered", path, t)
+	}
+	if k == reflect.Interface && t.NumMethod() > 0 {
+		return nil, fmt.Errorf("field %s: unsupported non-empty interface %v", path, t)
+	}
+	if _, ok := schemaKinds[k.String()]; ok {
+		return &SchemaType{Kind: k.String()}, nil
+	}
+
+	st := &SchemaType{Kind: k.String()}
+	var err error
+	switch k {
+	case reflect.Ptr, reflect.Slice:
+		st.Elem, err = toSchemaType(t.Elem(), path)
+	case reflect.Array:
+		st.Len = t.Len()
+		st.Elem, err = toSchemaType(t.Elem(), path)
+	case reflect.Map:
+		if st.Key, err = toSchemaType(t.Key(), path); err == nil {
+			st.Elem, err = toSchemaType(t.Elem(), path)
+		}
+	case reflect.Struct:
+		st.Fields, err = toSchemaFields(structFields(t), path)
+	default:
+		err = fmt.Errorf("field %s: unsupported type %v", path, t)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return st, nil
+}
+
+func (st *SchemaType) reflectType(path string) (reflect.Type, error) {
+	if st == nil {
+		return nil, fmt.Errorf("field %s: type is not specified", path)
+	}
+
+	if st.Named != "" {
+		t, ok := lookupNamedSchemaType(st.Named)
+		if !ok {
+			return nil, fmt.Errorf("field %s: named type %s is not registered", path, st.Named)
+		}
+		return t, nil
+	}
+
+	if t, ok := schemaKinds[st.Kind]; ok {
+		return t, nil
+	}
+
+	switch st.Kind {
+	case reflect.Ptr.String(), reflect.Slice.String(), reflect.Array.String():
+		et, err := st.Elem.reflectType(path)
+		if err != nil {
+			return nil, err
+		}
+		switch st.Kind {
+		case reflect.Ptr.String():
+			return reflect.PtrTo(et), nil
+		case reflect.Slice.String():
+			return reflect.SliceOf(et), nil
+		default:
+			if st.Len < 0 {
+				return nil, fmt.Errorf("field %s: invalid array length %d", path, st.Len)
+			}
+			return reflect.ArrayOf(st.Len, et), nil
+		}
+	case reflect.Map.String():
+		kt, err := st.Key.reflectType(path)
+		if err != nil {
+			return nil, err
+		}
+		if !kt.Comparable() {
+			return nil, fmt.Errorf("field %s: invalid map key type %v", path, kt)
+		}
+		et, err := st.Elem.reflectType(path)
+		if err != nil {
+			return nil, err
+		}
+		return reflect.MapOf(kt, et), nil
+	case reflect.Struct.String():
+		fields := make([]reflect.StructField, 0, len(st.Fields))
+		for _, f := range st.Fields {
+			p := joinPath(path, f.Name)
+			ft, err := f.Type.reflectType(p)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, reflect.StructField{
+				Name:      f.Name,
+				Type:      ft,
+				Tag:       reflect.StructTag(f.Tag),
+				Anonymous: f.Embedded,
+			})
+		}
+		t, err := structOf(fields)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", path, err)
+		}
+		return t, nil
+	}
+
+	return nil, fmt.Errorf("field %s: unsupported kind %q", path, st.Kind)
+}
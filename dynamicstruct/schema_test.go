@@ -0,0 +1,194 @@
+package dynamicstruct_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+
+	. "github.com/goldeneggg/structil/dynamicstruct"
+)
+
+func TestSchemaRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	nds, err := NewBuilder().
+		AddStringWithTag("Key", `json:"key"`).
+		AddMap("Attrs", SampleString, SampleFloat64).
+		BuildNonPtr()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	ds, err := NewBuilder().
+		SetStructName("Round").
+		AddStringWithTag("Name", `json:"name" yaml:"name"`).
+		AddType("Count", reflect.TypeOf(int64(0))).
+		AddType("Bytes", reflect.TypeOf([]byte(nil))).
+		AddType("Fixed", reflect.TypeOf([3]uint16{})).
+		AddType("CreatedAt", reflect.TypeOf(time.Time{})).
+		AddType("Timeout", reflect.TypeOf((*time.Duration)(nil))).
+		AddInterface("Any", true).
+		AddDynamicStructWithTag("Obj", nds, false, `json:"obj"`).
+		AddDynamicStructPtr("ObjPtr", nds).
+		AddDynamicStructSlice("Objs", nds).
+		AddMap("IntMap", SampleInt, SampleString).
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		marshal func() ([]byte, error)
+	}{
+		{name: "JSON", marshal: ds.MarshalSchema},
+		{name: "YAML", marshal: ds.MarshalSchemaYAML},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := tt.marshal()
+			if err != nil {
+				t.Fatalf("unexpected error is returned from marshal: %v", err)
+			}
+
+			got, err := UnmarshalSchema(data)
+			if err != nil {
+				t.Fatalf("unexpected error is returned from UnmarshalSchema: %v\n%s", err, data)
+			}
+
+			if got.Type() != ds.Type() {
+				t.Errorf("rebuilt type is not identical.\ngot:  %v\nwant: %v", got.Type(), ds.Type())
+			}
+			if got.Name() != ds.Name() || got.IsPtr() != ds.IsPtr() {
+				t.Errorf("unexpected name or pointer mode. got: %s, %v", got.Name(), got.IsPtr())
+			}
+			if d := cmp.Diff(fieldNames(got), fieldNames(ds)); d != "" {
+				t.Errorf("mismatch field order: (-got +want)\n%s", d)
+			}
+		})
+	}
+}
+
+func TestMarshalSchema(t *testing.T) {
+	t.Parallel()
+
+	ds, err := NewBuilder().
+		SetStructName("Small").
+		AddStringWithTag("Name", `json:"name"`).
+		AddSlice("IDs", SampleInt).
+		BuildNonPtr()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	got, err := ds.MarshalSchema()
+	if err != nil {
+		t.Fatalf("unexpected error is returned from MarshalSchema: %v", err)
+	}
+
+	want := `{
+  "version": 1,
+  "name": "Small",
+  "ptr": false,
+  "fields": [
+    {
+      "name": "Name",
+      "type": {
+        "kind": "string"
+      },
+      "tag": "json:\"name\""
+    },
+    {
+      "name": "IDs",
+      "type": {
+        "kind": "slice",
+        "elem": {
+          "kind": "int"
+        }
+      }
+    }
+  ]
+}`
+	if d := cmp.Diff(string(got), want); d != "" {
+		t.Fatalf("mismatch MarshalSchema: (-got +want)\n%s", d)
+	}
+}
+
+type (
+	schemaTestInt        int
+	schemaTestRegistered int
+)
+
+func TestSchemaErrors(t *testing.T) {
+	t.Parallel()
+
+	t.Run("UnsupportedField", func(t *testing.T) {
+		t.Parallel()
+
+		for _, typ := range []reflect.Type{
+			reflect.TypeOf(func() {}),
+			reflect.TypeOf(make(chan int)),
+			reflect.TypeOf((*error)(nil)).Elem(),
+			reflect.TypeOf(schemaTestInt(0)),
+		} {
+			ds, err := NewBuilder().AddType("F", typ).Build()
+			if err != nil {
+				t.Fatalf("unexpected error caused by Build: %v", err)
+			}
+			if _, err := ds.MarshalSchema(); err == nil {
+				t.Errorf("error is expected but it does not occur from MarshalSchema with %v", typ)
+			}
+		}
+	})
+
+	t.Run("InvalidSchema", func(t *testing.T) {
+		t.Parallel()
+
+		tests := []struct {
+			name string
+			data string
+		}{
+			{name: "Broken", data: `{`},
+			{name: "NoVersion", data: `{"name":"X","fields":[]}`},
+			{name: "FutureVersion", data: `{"version":99,"name":"X","fields":[]}`},
+			{name: "UnknownKind", data: `{"version":1,"fields":[{"name":"F","type":{"kind":"func"}}]}`},
+			{name: "NoType", data: `{"version":1,"fields":[{"name":"F"}]}`},
+			{name: "UnknownNamed", data: `{"version":1,"fields":[{"name":"F","type":{"kind":"struct","named":"foo.Bar"}}]}`},
+			{name: "InvalidMapKey", data: `{"version":1,"fields":[{"name":"F","type":{"kind":"map","key":{"kind":"slice","elem":{"kind":"int"}},"elem":{"kind":"int"}}}]}`},
+			{name: "UnexportedName", data: "version: 1\nfields:\n  - name: f\n    type:\n      kind: int\n"},
+		}
+
+		for _, tt := range tests {
+			if _, err := UnmarshalSchema([]byte(tt.data)); err == nil {
+				t.Errorf("%s: error is expected but it does not occur from UnmarshalSchema", tt.name)
+			}
+		}
+	})
+
+	t.Run("RegisteredType", func(t *testing.T) {
+		t.Parallel()
+
+		RegisterSchemaType("dynamicstruct_test.schemaTestRegistered", reflect.TypeOf(schemaTestRegistered(0)))
+		ds, err := NewBuilder().AddType("F", reflect.TypeOf(schemaTestRegistered(0))).Build()
+		if err != nil {
+			t.Fatalf("unexpected error caused by Build: %v", err)
+		}
+		data, err := ds.MarshalSchema()
+		if err != nil {
+			t.Fatalf("unexpected error is returned from MarshalSchema: %v", err)
+		}
+		got, err := UnmarshalSchema(data)
+		if err != nil {
+			t.Fatalf("unexpected error is returned from UnmarshalSchema: %v", err)
+		}
+		if got.Type() != ds.Type() {
+			t.Errorf("rebuilt type is not identical. got: %v, want: %v", got.Type(), ds.Type())
+		}
+	})
+}
@@ -0,0 +1,235 @@
+package dynamicstruct
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/iancoleman/strcase"
+)
+
+// Tag is a parsed struct tag (e.g. `json:"id,omitempty" yaml:"id"`).
+// The order of keys is kept. The zero value is an empty tag ready to use.
+type Tag struct {
+	entries []tagEntry
+}
+
+type tagEntry struct {
+	key   string
+	value string
+}
+
+// NewTag returns a new empty Tag.
+func NewTag() *Tag {
+	return &Tag{}
+}
+
+// ParseTag returns a Tag parsed from the conventional format of struct tags.
+// An error is returned if tag is malformed.
+func ParseTag(tag string) (*Tag, error) {
+	t := &Tag{}
+	s := tag
+	for {
+		s = strings.TrimLeft(s, " ")
+		if s == "" {
+			return t, nil
+		}
+
+		// scan to colon like reflect.StructTag.Lookup
+		i := 0
+		for i < len(s) && s[i] > ' ' && s[i] != ':' && s[i] != '"' && s[i] != 0x7f {
+			i++
+		}
+		if i == 0 || i+1 >= len(s) || s[i] != ':' || s[i+1] != '"' {
+			return nil, fmt.Errorf("malformed tag %q", tag)
+		}
+		key := s[:i]
+		s = s[i+1:]
+
+		// scan quoted string to find value
+		i = 1
+		for i < len(s) && s[i] != '"' {
+			if s[i] == '\\' {
+				i++
+			}
+			i++
+		}
+		if i >= len(s) {
+			return nil, fmt.Errorf("malformed tag %q", tag)
+		}
+		value, err := strconv.Unquote(s[:i+1])
+		if err != nil {
+			return nil, fmt.Errorf("malformed tag %q: %w", tag, err)
+		}
+		s = s[i+1:]
+
+		t.Set(key, value)
+	}
+}
+
+// Get returns the value of key (e.g. "id,omitempty") and a boolean indicating if key exists.
+func (t *Tag) Get(key string) (string, bool) {
+	if i := t.index(key); i >= 0 {
+		return t.entries[i].value, true
+	}
+	return "", false
+}
+
+// Name returns the name part of the value of key (e.g. "id" of "id,omitempty").
+func (t *Tag) Name(key string) string {
+	v, _ := t.Get(key)
+	name, _, _ := strings.Cut(v, ",")
+	return name
+}
+
+// HasOption reports whether the value of key has the option opt (e.g. "omitempty").
+func (t *Tag) HasOption(key string, opt string) bool {
+	v, _ := t.Get(key)
+	opts := strings.Split(v, ",")
+	for _, o := range opts[1:] {
+		if o == opt {
+			return true
+		}
+	}
+	return false
+}
+
+// Set sets the value of key by name and options (e.g. Set("json", "id", "omitempty") for `json:"id,omitempty"`).
+// If key already exists, the value is replaced in the same position. Otherwise key is appended.
+func (t *Tag) Set(key string, name string, opts ...string) *Tag {
+	value := strings.Join(append([]string{name}, opts...), ",")
+	if i := t.index(key); i >= 0 {
+		t.entries[i].value = value
+		return t
+	}
+
+	t.entries = append(t.entries, tagEntry{key: key, value: value})
+	return t
+}
+
+// AddOption adds the option opt to the value of key if key exists and does not have opt.
+// Ignored values ("-") are not changed.
+func (t *Tag) AddOption(key string, opt string) *Tag {
+	i := t.index(key)
+	if i < 0 || t.entries[i].value == "-" || t.HasOption(key, opt) {
+		return t
+	}
+
+	t.entries[i].value += "," + opt
+	return t
+}
+
+// Del deletes key.
+func (t *Tag) Del(key string) *Tag {
+	if i := t.index(key); i >= 0 {
+		t.entries = append(t.entries[:i], t.entries[i+1:]...)
+	}
+	return t
+}
+
+// Keys returns the keys in order.
+func (t *Tag) Keys() []string {
+	keys := make([]string, len(t.entries))
+	for i, e := range t.entries {
+		keys[i] = e.key
+	}
+	return keys
+}
+
+// String returns the tag in the conventional format. Values are quoted by strconv.Quote.
+func (t *Tag) String() string {
+	parts := make([]string, len(t.entries))
+	for i, e := range t.entries {
+		parts[i] = e.key + ":" + strconv.Quote(e.value)
+	}
+	return strings.Join(parts, " ")
+}
+
+// StructTag returns the tag as reflect.StructTag.
+func (t *Tag) StructTag() reflect.StructTag {
+	return reflect.StructTag(t.String())
+}
+
+func (t *Tag) index(key string) int {
+	for i, e := range t.entries {
+		if e.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// TagNameFunc returns the tag name for a field name.
+type TagNameFunc func(fieldName string) string
+
+var (
+	// SnakeCase converts a field name to snake_case (e.g. "UserName" to "user_name").
+	SnakeCase TagNameFunc = strcase.ToSnake
+
+	// LowerCamelCase converts a field name to lowerCamelCase (e.g. "UserName" to "userName").
+	LowerCamelCase TagNameFunc = strcase.ToLowerCamel
+
+	// KebabCase converts a field name to kebab-case (e.g. "UserName" to "user-name").
+	KebabCase TagNameFunc = strcase.ToKebab
+)
+
+// AddTag returns a Builder that was added the key and value to the tag of the specific field.
+// Unlike SetTag, the other keys of the existing tag are kept. If key already exists, the value is replaced.
+// If the existing tag is malformed, the error is returned by Build.
+func (b *Builder) AddTag(name string, key string, value string) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.updateTag(name, func(t *Tag) {
+		t.Set(key, value)
+	})
+	return b
+}
+
+// DelTag returns a Builder that was deleted the key from the tag of the specific field.
+func (b *Builder) DelTag(name string, key string) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.updateTag(name, func(t *Tag) {
+		t.Del(key)
+	})
+	return b
+}
+
+// AddTagToAll returns a Builder that was added the key to the tags of all fields.
+// The name in the value is converted from the field name by f, and opts are appended (e.g. "omitempty").
+// The fields that already have the key are not changed.
+//
+// e.g. AddTagToAll("db", SnakeCase) adds `db:"user_name"` to the field "UserName".
+func (b *Builder) AddTagToAll(key string, f TagNameFunc, opts ...string) *Builder {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, name := range b.keys {
+		b.updateTag(name, func(t *Tag) {
+			if _, ok := t.Get(key); !ok {
+				t.Set(key, f(name), opts...)
+			}
+		})
+	}
+	return b
+}
+
+// updateTag must be called with b.mu held.
+func (b *Builder) updateTag(name string, f func(t *Tag)) {
+	bf := b.getFieldMap(name)
+	if bf == nil {
+		return
+	}
+
+	t, err := ParseTag(string(bf.tag))
+	if err != nil {
+		b.setErr(fmt.Errorf("field %s: %w", name, err))
+		return
+	}
+
+	f(t)
+	bf.tag = t.StructTag()
+}
@@ -0,0 +1,158 @@
+package dynamicstruct_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	. "github.com/goldeneggg/structil/dynamicstruct"
+)
+
+func TestTag(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		tag     string
+		f       func(tag *Tag)
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "parse and format",
+			tag:  `json:"id,omitempty"  yaml:"id"`,
+			f:    func(tag *Tag) {},
+			want: `json:"id,omitempty" yaml:"id"`,
+		},
+		{
+			name: "set existing key keeps position",
+			tag:  `json:"id" yaml:"id"`,
+			f:    func(tag *Tag) { tag.Set("json", "user_id", "omitempty") },
+			want: `json:"user_id,omitempty" yaml:"id"`,
+		},
+		{
+			name: "set new key is appended",
+			tag:  `json:"id"`,
+			f:    func(tag *Tag) { tag.Set("db", "id") },
+			want: `json:"id" db:"id"`,
+		},
+		{
+			name: "del",
+			tag:  `json:"id" yaml:"id" db:"id"`,
+			f:    func(tag *Tag) { tag.Del("yaml").Del("unknown") },
+			want: `json:"id" db:"id"`,
+		},
+		{
+			name: "add option",
+			tag:  `json:"id" yaml:"-" db:"id,omitempty"`,
+			f: func(tag *Tag) {
+				tag.AddOption("json", "omitempty").AddOption("yaml", "omitempty").AddOption("db", "omitempty").AddOption("xml", "omitempty")
+			},
+			want: `json:"id,omitempty" yaml:"-" db:"id,omitempty"`,
+		},
+		{
+			name: "quoted value",
+			tag:  `json:"a\"b"`,
+			f:    func(tag *Tag) {},
+			want: `json:"a\"b"`,
+		},
+		{
+			name:    "missing quote",
+			tag:     `json:id`,
+			wantErr: true,
+		},
+		{
+			name:    "unterminated value",
+			tag:     `json:"id`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			tag, err := ParseTag(tt.tag)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("expected error is not occurred")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error caused by ParseTag: %v", err)
+			}
+
+			tt.f(tag)
+			if d := cmp.Diff(tag.String(), tt.want); d != "" {
+				t.Errorf("mismatch: (-got +want)\n%s", d)
+			}
+		})
+	}
+}
+
+func TestTagGet(t *testing.T) {
+	t.Parallel()
+
+	tag, err := ParseTag(`json:"id,omitempty,string" yaml:"-"`)
+	if err != nil {
+		t.Fatalf("unexpected error caused by ParseTag: %v", err)
+	}
+
+	if v, ok := tag.Get("json"); !ok || v != "id,omitempty,string" {
+		t.Errorf("unexpected Get(json): %s, %v", v, ok)
+	}
+	if _, ok := tag.Get("db"); ok {
+		t.Errorf("Get(db) should not exist")
+	}
+	if tag.Name("json") != "id" {
+		t.Errorf("unexpected Name(json): %s", tag.Name("json"))
+	}
+	if !tag.HasOption("json", "string") || tag.HasOption("json", "id") {
+		t.Errorf("unexpected HasOption")
+	}
+	if d := cmp.Diff(tag.Keys(), []string{"json", "yaml"}); d != "" {
+		t.Errorf("mismatch Keys: (-got +want)\n%s", d)
+	}
+	if got := tag.StructTag().Get("json"); got != "id,omitempty,string" {
+		t.Errorf("unexpected StructTag: %s", got)
+	}
+}
+
+func TestBuilderAddTag(t *testing.T) {
+	t.Parallel()
+
+	ds, err := NewBuilder().
+		AddStringWithTag("UserName", `json:"user_name"`).
+		AddIntWithTag("ID", `db:"user_id"`).
+		AddBool("IsAdmin").
+		AddTag("UserName", "yaml", "user_name").
+		AddTag("UserName", "json", "userName,omitempty").
+		DelTag("ID", "unknown").
+		AddTagToAll("db", SnakeCase).
+		AddTagToAll("json", LowerCamelCase, "omitempty").
+		AddTag("Unknown", "json", "unknown").
+		Build()
+	if err != nil {
+		t.Fatalf("unexpected error caused by Build: %v", err)
+	}
+
+	want := map[string]string{
+		"UserName": `json:"userName,omitempty" yaml:"user_name" db:"user_name"`,
+		"ID":       `db:"user_id" json:"id,omitempty"`,
+		"IsAdmin":  `db:"is_admin" json:"isAdmin,omitempty"`,
+	}
+	for name, wantTag := range want {
+		f, _ := ds.FieldByName(name)
+		if d := cmp.Diff(string(f.Tag), wantTag); d != "" {
+			t.Errorf("mismatch tag of %s: (-got +want)\n%s", name, d)
+		}
+	}
+
+	_, err = NewBuilder().AddStringWithTag("A", `json:a`).AddTag("A", "yaml", "a").Build()
+	if err == nil || !strings.Contains(err.Error(), "field A: malformed tag") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -5,6 +5,7 @@ go 1.19
 require (
 	github.com/google/go-cmp v0.5.8
 	github.com/iancoleman/strcase v0.2.0
+	github.com/pelletier/go-toml/v2 v2.0.2
 	github.com/spf13/viper v1.12.0
 	gopkg.in/yaml.v3 v3.0.1
 )
@@ -15,7 +16,6 @@ require (
 	github.com/magiconair/properties v1.8.6 // indirect
 	github.com/mitchellh/mapstructure v1.5.0 // indirect
 	github.com/pelletier/go-toml v1.9.5 // indirect
-	github.com/pelletier/go-toml/v2 v2.0.2 // indirect
 	github.com/spf13/afero v1.9.2 // indirect
 	github.com/spf13/cast v1.5.0 // indirect
 	github.com/spf13/jwalterweatherman v1.1.0 // indirect